@@ -32,7 +32,7 @@ func (c *Cli) InitProject() (*project.Project, error) {
 
 	cfgPath, err := project.Discover()
 	if err != nil {
-		return nil, util.NewReadableError(err, "Could not find sst.config.ts")
+		return nil, util.NewReadableError(err, "Could not find sst.config.ts, sst.config.mjs, or sst.config.js. Pass in the path to your config with --config.")
 	}
 
 	stage, err := c.Stage(cfgPath)
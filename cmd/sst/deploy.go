@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/sst/ion/cmd/sst/cli"
-	"github.com/sst/ion/cmd/sst/mosaic/ui"
+	"github.com/sst/ion/internal/util"
 	"github.com/sst/ion/pkg/bus"
 	"github.com/sst/ion/pkg/project"
 	"github.com/sst/ion/pkg/server"
@@ -22,12 +24,56 @@ func CmdDeploy(c *cli.Cli) error {
 	if c.String("target") != "" {
 		target = strings.Split(c.String("target"), ",")
 	}
+	allowDestroy := []string{}
+	if c.String("allow-destroy") != "" {
+		allowDestroy = strings.Split(c.String("allow-destroy"), ",")
+	}
+	replace := []string{}
+	if c.String("replace") != "" {
+		replace = strings.Split(c.String("replace"), ",")
+	}
+	exclude := []string{}
+	if c.String("exclude") != "" {
+		exclude = strings.Split(c.String("exclude"), ",")
+	}
+
+	retry := 0
+	if c.String("retry") != "" {
+		retry, err = strconv.Atoi(c.String("retry"))
+		if err != nil || retry < 0 {
+			return util.NewReadableError(err, "The --retry flag needs to be a number")
+		}
+	}
+	parallel := 0
+	if c.String("parallel") != "" {
+		parallel, err = strconv.Atoi(c.String("parallel"))
+		if err != nil || parallel < 1 {
+			return util.NewReadableError(err, "The --parallel flag needs to be a number greater than 0")
+		}
+	}
+
+	var plan *project.Plan
+	if c.String("plan") != "" {
+		if c.String("target") != "" {
+			return util.NewReadableError(nil, "The targets are set by the plan, --target can't be used with --plan")
+		}
+		if len(replace) > 0 || len(exclude) > 0 {
+			return util.NewReadableError(nil, "The changes are set by the plan, --replace and --exclude can't be used with --plan")
+		}
+		plan, err = project.ReadPlan(c.String("plan"))
+		if err != nil {
+			return util.NewReadableError(err, "Could not read plan "+c.String("plan"))
+		}
+	}
 
 	var wg errgroup.Group
 	defer wg.Wait()
 	out := make(chan interface{})
 	defer close(out)
-	ui := ui.New(c.Context)
+	ui, err := newRenderer(c)
+	if err != nil {
+		return err
+	}
 	s, err := server.New()
 	if err != nil {
 		return err
@@ -39,19 +85,35 @@ func CmdDeploy(c *cli.Cli) error {
 	events := bus.SubscribeAll()
 	defer close(events)
 	wg.Go(func() error {
+		defer ui.Destroy()
 		for evt := range events {
 			ui.Event(evt)
 		}
 		return nil
 	})
-	defer ui.Destroy()
 	defer c.Cancel()
 	err = p.Run(c.Context, &project.StackInput{
-		Command:    "deploy",
-		Target:     target,
-		ServerPort: s.Port,
-		Verbose:    c.Bool("verbose"),
+		Command:         "deploy",
+		Target:          target,
+		ServerPort:      s.Port,
+		Verbose:         c.Bool("verbose"),
+		AllowDestroy:    allowDestroy,
+		Retry:           retry,
+		Parallel:        parallel,
+		Replace:         replace,
+		Exclude:         exclude,
+		ContinueOnError: c.Bool("continue-on-error"),
+		Plan:            plan,
 	})
+	if errors.Is(err, project.ErrPlanMismatch) {
+		return util.NewReadableError(err, err.Error()+"\n\nRun \"sst diff --out\" again and review the new plan.")
+	}
+	if errors.Is(err, project.ErrExcludedEverything) {
+		return util.NewReadableError(err, "Every resource is excluded, there's nothing to deploy")
+	}
+	if errors.Is(err, project.ErrPlanConfigChanged) {
+		return util.NewReadableError(err, "The config changed since the plan was created. Run \"sst diff --out\" again and review the new plan.")
+	}
 	if err != nil {
 		return err
 	}
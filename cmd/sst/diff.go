@@ -9,6 +9,7 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/sst/ion/cmd/sst/cli"
 	"github.com/sst/ion/cmd/sst/mosaic/ui"
+	"github.com/sst/ion/internal/util"
 	"github.com/sst/ion/pkg/bus"
 	"github.com/sst/ion/pkg/project"
 	"github.com/sst/ion/pkg/server"
@@ -17,6 +18,9 @@ import (
 )
 
 func CmdDiff(c *cli.Cli) error {
+	if c.String("out") != "" && c.Bool("dev") {
+		return util.NewReadableError(nil, "A plan can't be saved when comparing to sst dev")
+	}
 	p, err := c.InitProject()
 	if err != nil {
 		return err
@@ -31,7 +35,10 @@ func CmdDiff(c *cli.Cli) error {
 	var wg errgroup.Group
 	defer wg.Wait()
 	outputs := []*apitype.ResOutputsEvent{}
-	u := ui.New(c.Context)
+	r, err := newRenderer(c)
+	if err != nil {
+		return err
+	}
 	s, err := server.New()
 	if err != nil {
 		return err
@@ -44,8 +51,9 @@ func CmdDiff(c *cli.Cli) error {
 	events := bus.SubscribeAll()
 	defer close(events)
 	wg.Go(func() error {
+		defer r.Destroy()
 		for evt := range events {
-			u.Event(evt)
+			r.Event(evt)
 			switch evt := evt.(type) {
 			case *apitype.ResOutputsEvent:
 				outputs = append(outputs, evt)
@@ -53,7 +61,6 @@ func CmdDiff(c *cli.Cli) error {
 		}
 		return nil
 	})
-	defer u.Destroy()
 	defer c.Cancel()
 	err = p.Run(c.Context, &project.StackInput{
 		Command:    "diff",
@@ -61,10 +68,15 @@ func CmdDiff(c *cli.Cli) error {
 		Dev:        c.Bool("dev"),
 		Target:     target,
 		Verbose:    c.Bool("verbose"),
+		SavePlan:   c.String("out"),
 	})
 	if err != nil {
 		return err
 	}
+	u, ok := r.(*ui.UI)
+	if !ok {
+		return nil
+	}
 	if len(outputs) == 0 {
 		fmt.Println(
 			ui.TEXT_HIGHLIGHT_BOLD.Render("➜"),
@@ -73,6 +85,12 @@ func CmdDiff(c *cli.Cli) error {
 		fmt.Println()
 		return nil
 	}
+	printDiff(u, outputs)
+	return nil
+}
+
+// printDiff prints the properties that changed for each resource
+func printDiff(u *ui.UI, outputs []*apitype.ResOutputsEvent) {
 	for _, output := range outputs {
 		icon := ""
 		if output.Metadata.Op == apitype.OpImport {
@@ -90,18 +108,29 @@ func CmdDiff(c *cli.Cli) error {
 		if output.Metadata.Op == apitype.OpCreate {
 			icon = ui.TEXT_SUCCESS_BOLD.Render("+")
 		}
+		if output.Metadata.Op == apitype.OpRefresh && ui.Drifted(output) {
+			icon = ui.TEXT_WARNING_BOLD.Render("*")
+		}
 		if icon == "" {
 			continue
 		}
 
 		fmt.Println(icon, "", ui.TEXT_NORMAL_BOLD.Render(u.FormatURN(output.Metadata.URN)))
-		sorted := make([]string, 0, len(output.Metadata.DetailedDiff))
-		for path := range output.Metadata.DetailedDiff {
+		detailed := output.Metadata.DetailedDiff
+		if len(detailed) == 0 && output.Metadata.Op == apitype.OpRefresh {
+			// refreshes don't always have a detailed diff
+			detailed = map[string]apitype.PropertyDiff{}
+			for _, key := range output.Metadata.Diffs {
+				detailed[key] = apitype.PropertyDiff{Kind: apitype.DiffUpdate}
+			}
+		}
+		sorted := make([]string, 0, len(detailed))
+		for path := range detailed {
 			sorted = append(sorted, path)
 		}
 		sort.Strings(sorted)
 		for _, path := range sorted {
-			diff := output.Metadata.DetailedDiff[path]
+			diff := detailed[path]
 			label := ""
 			if diff.Kind == apitype.DiffUpdate {
 				label = ui.TEXT_WARNING_BOLD.Render("*")
@@ -149,5 +178,4 @@ func CmdDiff(c *cli.Cli) error {
 		}
 		fmt.Println()
 	}
-	return nil
 }
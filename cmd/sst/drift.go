@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/cmd/sst/cli"
+	"github.com/sst/ion/cmd/sst/mosaic/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/bus"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/server"
+	"golang.org/x/sync/errgroup"
+)
+
+func CmdDrift(c *cli.Cli) error {
+	p, err := c.InitProject()
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	target := []string{}
+	if c.String("target") != "" {
+		target = strings.Split(c.String("target"), ",")
+	}
+
+	var wg errgroup.Group
+	defer wg.Wait()
+	drifted := []*apitype.ResOutputsEvent{}
+	r, err := newRenderer(c)
+	if err != nil {
+		return err
+	}
+	s, err := server.New()
+	if err != nil {
+		return err
+	}
+	wg.Go(func() error {
+		defer c.Cancel()
+		return s.Start(c.Context, p)
+	})
+
+	events := bus.SubscribeAll()
+	defer close(events)
+	wg.Go(func() error {
+		defer r.Destroy()
+		for evt := range events {
+			r.Event(evt)
+			switch evt := evt.(type) {
+			case *apitype.ResOutputsEvent:
+				if ui.Drifted(evt) {
+					drifted = append(drifted, evt)
+				}
+			}
+		}
+		return nil
+	})
+	defer c.Cancel()
+	err = p.Run(c.Context, &project.StackInput{
+		Command:    "drift",
+		ServerPort: s.Port,
+		Target:     target,
+		Verbose:    c.Bool("verbose"),
+	})
+	if err != nil {
+		return err
+	}
+	if len(drifted) == 0 {
+		return nil
+	}
+	if u, ok := r.(*ui.UI); ok {
+		printDiff(u, drifted)
+	}
+	return util.NewReadableError(nil, fmt.Sprintf("Found drift in %d resources", len(drifted)))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/cli"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+)
+
+func CmdCommonErrors(c *cli.Cli) error {
+	errors, err := project.CommonErrors()
+	if err != nil {
+		return err
+	}
+	code := c.Positional(0)
+	if code != "" {
+		filtered := []project.CommonError{}
+		for _, item := range errors {
+			if strings.EqualFold(item.Code, code) {
+				filtered = append(filtered, item)
+			}
+		}
+		if len(filtered) == 0 {
+			return util.NewReadableError(nil, "No common error with the code "+code)
+		}
+		errors = filtered
+	}
+
+	switch c.String("format") {
+	case "json":
+		data, err := json.MarshalIndent(errors, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case "", "text":
+	default:
+		return util.NewReadableError(nil, "Unknown format "+c.String("format")+", use text or json")
+	}
+
+	for i, item := range errors {
+		if i > 0 {
+			fmt.Println()
+		}
+		color.New(color.Bold).Println(item.Code)
+		color.New(color.FgHiBlack).Println(item.Message)
+		lines := item.Help()
+		if code != "" {
+			lines = item.Long
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sst/ion/cmd/sst/cli"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+)
+
+func CmdGraph(c *cli.Cli) error {
+	p, err := c.InitProject()
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	format := c.String("format")
+	if format == "" {
+		format = "mermaid"
+	}
+	if format != "mermaid" && format != "dot" && format != "json" {
+		return util.NewReadableError(nil, "Unknown format "+format+", use mermaid, dot, or json")
+	}
+
+	complete, err := p.GetCompleted(c.Context)
+	if err != nil {
+		return err
+	}
+	graph, err := project.BuildGraph(complete.Resources, c.String("component"))
+	if errors.Is(err, project.ErrComponentNotFound) {
+		return util.NewReadableError(err, "Could not find a component named "+c.String("component"))
+	}
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "dot":
+		fmt.Println(graph.DOT())
+	case "mermaid":
+		fmt.Println(graph.Mermaid())
+	}
+	return nil
+}
@@ -102,6 +102,15 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	if c.String("config") != "" {
+		cfgPath, err := filepath.Abs(c.String("config"))
+		if err != nil {
+			return err
+		}
+		// set in the environment as well so the commands sst runs pick it up
+		flag.SST_CONFIG = cfgPath
+		os.Setenv("SST_CONFIG", cfgPath)
+	}
 	_, err = user.Current()
 	if err != nil {
 		return err
@@ -205,6 +214,29 @@ var root = &cli.Command{
 		}, "\n"),
 	},
 	Flags: []cli.Flag{
+		{
+			Name: "config",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Path to the config file",
+				Long: strings.Join([]string{
+					"Set the path to the config file of your app.",
+					"",
+					"By default, the CLI looks for an `sst.config.ts`, `sst.config.mjs`, or `sst.config.js` in the current directory and its parents.",
+					"",
+					"```bash frame=\"none\"",
+					"sst deploy --config apps/api/sst.config.ts",
+					"```",
+					"",
+					"This is useful for monorepos with multiple apps. A config that's not named `sst.config` gets its own `.sst/apps/` directory, so two apps in the same directory don't collide.",
+					"",
+					"You can also use the `SST_CONFIG` environment variable.",
+					"```bash frame=\"none\"",
+					"SST_CONFIG=sst.api.config.ts sst deploy",
+					"```",
+				}, "\n"),
+			},
+		},
 		{
 			Name: "stage",
 			Type: "string",
@@ -490,9 +522,147 @@ var root = &cli.Command{
 					"```bash frame=\"none\"",
 					"sst deploy --target urn:pulumi:prod::www::sst:aws:Astro::Astro,urn:pulumi:prod::www::sst:aws:Bucket::Assets",
 					"```",
+					"",
+					"To deploy exactly what was reviewed, pass in a plan created with `sst diff --out`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst deploy --stage production --plan plan.json",
+					"```",
+					"",
+					"It checks the changes against the plan before deploying anything, and fails if the config changed or if the changes don't match the plan.",
+					"",
+					"If a resource listed in `protect` in your `sst.config.ts` would be deleted or replaced, the deploy fails before making any changes. Pass in its URN with `--allow-destroy` to let it through.",
+					"",
+					"You can also add an `sst.policy.json` next to your `sst.config.ts` with rules for the inputs of your resources. Every resource is checked against them before deploying and the deploy fails if any rule with the `error` level is violated.",
+					"",
+					"```json title=\"sst.policy.json\"",
+					"{",
+					"  \"rules\": [",
+					"    {",
+					"      \"name\": \"no-public-function-urls\",",
+					"      \"message\": \"Function URLs must use IAM auth\",",
+					"      \"types\": [\"aws:lambda/functionUrl:FunctionUrl\"],",
+					"      \"property\": \"authorizationType\",",
+					"      \"notEquals\": \"NONE\"",
+					"    }",
+					"  ]",
+					"}",
+					"```",
+					"",
+					"Each rule checks a `property` of the resources of the given `types`, which can end in `*`. The property can be `required`, or have to match `equals`, `notEquals`, or `oneOf`. Rules with the `warn` level are only printed.",
 				}, "\n"),
 			},
 			Flags: []cli.Flag{
+				{
+					Name: "allow-destroy",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Comma separated list of protected URNs to allow destroying",
+						Long: strings.Join([]string{
+							"Comma separated list of protected URNs that are allowed to be deleted or replaced.",
+							"",
+							"Resources listed in `protect` in your `sst.config.ts` can't be deleted or replaced unless they are passed in here.",
+							"",
+							"```bash frame=\"none\"",
+							"sst deploy --allow-destroy urn:pulumi:prod::www::sst:aws:Postgres$aws:rds/instance:Instance::Database",
+							"```",
+						}, "\n"),
+					},
+				},
+				{
+					Name: "parallel",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Number of resources to deploy at once",
+						Long: strings.Join([]string{
+							"Limit how many resources are deployed at once. By default, there's no limit.",
+							"",
+							"```bash frame=\"none\"",
+							"sst deploy --parallel 8",
+							"```",
+						}, "\n"),
+					},
+				},
+				{
+					Name: "replace",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Comma separated list of URNs to force replace",
+						Long: strings.Join([]string{
+							"Comma separated list of URNs to replace, even if they haven't changed. This is useful if a resource is broken and needs to be recreated.",
+							"",
+							"```bash frame=\"none\"",
+							"sst deploy --replace urn:pulumi:prod::www::sst:aws:Function$aws:lambda/function:Function::MyFunction",
+							"```",
+						}, "\n"),
+					},
+				},
+				{
+					Name: "exclude",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Comma separated list of URNs to skip",
+						Long: strings.Join([]string{
+							"Comma separated list of URNs to skip. All the other resources, or the ones in `--target`, are deployed.",
+							"",
+							"```bash frame=\"none\"",
+							"sst deploy --exclude urn:pulumi:prod::www::sst:aws:Astro::Astro",
+							"```",
+							"",
+							"The resources that depend on an excluded resource aren't skipped automatically, so they might fail if it hasn't been created yet.",
+						}, "\n"),
+					},
+				},
+				{
+					Name: "continue-on-error",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "Keep deploying after a resource fails",
+						Long:  "Keep deploying the resources that don't depend on a resource that failed. By default, the deploy stops at the first failure.",
+					},
+				},
+				{
+					Name: "retry",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Retry transient failures this many times",
+						Long: strings.Join([]string{
+							"Retry the resources that failed with a transient error, like throttling, this many times. Defaults to `0`.",
+							"",
+							"Only the resources that failed are deployed again, with an increasing delay between the attempts. If any of the errors isn't transient, the deploy fails right away.",
+							"",
+							"```bash frame=\"none\"",
+							"sst deploy --retry 3",
+							"```",
+							"",
+							"The transient errors are marked in the [common errors](/docs/common-errors) list.",
+						}, "\n"),
+					},
+				},
+				{
+					Name: "plan",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Only deploy the changes in this plan",
+						Long:  "Path to a plan created with `sst diff --out`. The deploy fails if the changes don't match it.",
+					},
+				},
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, text or json",
+						Long: strings.Join([]string{
+							"Set the output format. Defaults to `text`.",
+							"",
+							"With `json`, every event is printed to stdout as a line of JSON instead. Each line has a `version`, `type`, `timestamp`, and `data` field. The last line is always a `summary` with the status, the resources that changed, and any errors.",
+							"",
+							"```bash frame=\"none\"",
+							"sst deploy --format json",
+							"```",
+						}, "\n"),
+					},
+				},
 				{
 					Name: "target",
 					Description: cli.Description{
@@ -543,9 +713,41 @@ var root = &cli.Command{
 					"```",
 					"",
 					"This is useful because in dev mode, you app is deployed a little differently.",
+					"",
+					"Save the changes to a plan with `--out`. It can be reviewed and then deployed with `sst deploy --plan`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst diff --stage production --out plan.json",
+					"```",
+					"",
+					"If there's an `sst.policy.json` next to your `sst.config.ts`, the changes are also checked against its rules.",
 				}, "\n"),
 			},
 			Flags: []cli.Flag{
+				{
+					Name: "out",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Save the changes to a plan file",
+						Long:  "Save the changes to a plan file that can be passed to `sst deploy --plan`.",
+					},
+				},
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, text or json",
+						Long: strings.Join([]string{
+							"Set the output format. Defaults to `text`.",
+							"",
+							"With `json`, every event is printed to stdout as a line of JSON instead. Each line has a `version`, `type`, `timestamp`, and `data` field. The last line is always a `summary` with the status, the resources that changed, and any errors.",
+							"",
+							"```bash frame=\"none\"",
+							"sst diff --format json",
+							"```",
+						}, "\n"),
+					},
+				},
 				{
 					Name: "target",
 					Description: cli.Description{
@@ -574,6 +776,61 @@ var root = &cli.Command{
 			},
 			Run: CmdDiff,
 		},
+		{
+			Name: "drift",
+			Description: cli.Description{
+				Short: "Check for changes made outside of SST",
+				Long: strings.Join([]string{
+					"Compares your live resources to the state of your app and lists every resource that was changed outside of SST.",
+					"",
+					"```bash frame=\"none\"",
+					"sst drift --stage production",
+					"```",
+					"",
+					"Unlike `sst refresh`, this doesn't update the state. It exits with a non-zero code if any drift is found, so it can be run on a schedule in CI.",
+					"",
+					"Optionally, check a specific set of resources by passing in a list of their URNs.",
+					"",
+					"```bash frame=\"none\"",
+					"sst drift --target urn:pulumi:prod::www::sst:aws:Astro::Astro,urn:pulumi:prod::www::sst:aws:Bucket::Assets",
+					"```",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "target",
+					Description: cli.Description{
+						Short: "Comma separated list of target URNs",
+						Long:  "Comma separated list of target URNs.",
+					},
+				},
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, text or json",
+						Long: strings.Join([]string{
+							"Set the output format. Defaults to `text`.",
+							"",
+							"With `json`, every event is printed to stdout as a line of JSON instead. The resources that drifted are listed in the `changes` of the final `summary` line, and its `status` is `drifted` instead of `success`.",
+							"",
+							"```bash frame=\"none\"",
+							"sst drift --format json",
+							"```",
+						}, "\n"),
+					},
+				},
+			},
+			Examples: []cli.Example{
+				{
+					Content: "sst drift --stage production",
+					Description: cli.Description{
+						Short: "Check production for drift",
+					},
+				},
+			},
+			Run: CmdDrift,
+		},
 		{
 			Name: "add",
 			Description: cli.Description{
@@ -707,9 +964,62 @@ var root = &cli.Command{
 					"Behind the scenes, it installs the packages for your providers and adds the providers to your globals.",
 					"",
 					"If you don't have a version specified for your providers in your `sst.config.ts`, it'll install their latest versions.",
+					"",
+					"The installed versions and the integrity hashes of their packages are saved to a `sst.lock.json` next to your config. Commit this file so the same versions are installed everywhere.",
+					"",
+					"#### Offline",
+					"",
+					"To install on a machine without internet access, create a bundle on a machine that has access.",
+					"",
+					"```bash frame=\"none\"",
+					"sst install --bundle sst-bundle.tgz",
+					"```",
+					"",
+					"The bundle has the Pulumi CLI, bun, the Pulumi plugins, and the installed providers. It needs to be created on the same OS and architecture, and with the same version of `sst`.",
+					"",
+					"Then point `SST_OFFLINE` to the bundle, or to a directory it was extracted to, and everything is installed from it instead of being downloaded.",
+					"",
+					"```bash frame=\"none\"",
+					"SST_OFFLINE=sst-bundle.tgz sst install",
+					"```",
 				}, "\n"),
 			},
+			Flags: []cli.Flag{
+				{
+					Name: "frozen",
+					Type: "bool",
+					Description: cli.Description{
+						Short: "Fail if the lock needs to be updated",
+						Long: strings.Join([]string{
+							"Install the providers exactly as they are in the lock, and fail instead of updating it if it doesn't match your `sst.config.ts`. The provider packages are downloaded and checked against the integrity hashes in the lock, and those exact tarballs are what gets installed. Their own dependencies are installed by your package manager as usual.",
+							"",
+							"```bash frame=\"none\"",
+							"sst install --frozen",
+							"```",
+							"",
+							"This is useful in CI, like `npm ci`. Set `SST_FROZEN=1` to do the same for the install that runs before commands like `sst deploy`.",
+						}, "\n"),
+					},
+				},
+				{
+					Name: "bundle",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Create an offline bundle",
+						Long: strings.Join([]string{
+							"After installing, write everything that was downloaded to a tarball that can be used with `SST_OFFLINE`.",
+							"",
+							"```bash frame=\"none\"",
+							"sst install --bundle sst-bundle.tgz",
+							"```",
+						}, "\n"),
+					},
+				},
+			},
 			Run: func(cli *cli.Cli) error {
+				if cli.Bool("frozen") {
+					flag.SST_FROZEN = true
+				}
 				cfgPath, err := project.Discover()
 				if err != nil {
 					return err
@@ -744,6 +1054,19 @@ var root = &cli.Command{
 				if err != nil {
 					return err
 				}
+				if out := cli.String("bundle"); out != "" {
+					if global.Offline() {
+						return util.NewReadableError(nil, "Cannot create a bundle with SST_OFFLINE set")
+					}
+					spin.Suffix = "  Creating bundle..."
+					err = p.Bundle(out)
+					if err != nil {
+						return util.NewReadableError(err, "Could not create bundle: "+err.Error())
+					}
+					spin.Stop()
+					ui.Success("Created bundle " + out)
+					return nil
+				}
 				spin.Stop()
 				ui.Success("Installed providers")
 				return nil
@@ -758,6 +1081,17 @@ var root = &cli.Command{
 					"",
 					"The `--fallback` flag can be used to manage the fallback values of a secret.",
 					"Applies to all the sub-commands in `sst secret`.",
+					"",
+					"By default secrets are encrypted with the passphrase of the stage. You can instead encrypt them with a per-stage data key that's wrapped by a key provider.",
+					"",
+					"```bash frame=\"none\"",
+					"SST_KEY_PROVIDER=kms SST_KMS_KEY_ID=arn:aws:kms:us-east-1:123456789012:key/1234 sst secret set MySecret value",
+					"```",
+					"",
+					"- `kms` wraps the data key with the AWS KMS key in `SST_KMS_KEY_ID`. The app and stage are passed in as the encryption context. Use `SST_KMS_ENDPOINT` to point it at a different endpoint.",
+					"- `keyfile` wraps the data key with a key stored on your machine. It's created if it doesn't exist. Set the path with `SST_KEYFILE`.",
+					"",
+					"Once a stage has a data key, it's used from then on and existing secrets are re-encrypted with it.",
 				}, "\n"),
 			},
 			Flags: []cli.Flag{
@@ -775,6 +1109,10 @@ var root = &cli.Command{
 				CmdSecretRemove,
 				CmdSecretLoad,
 				CmdSecretList,
+				CmdSecretExport,
+				CmdSecretCopy,
+				CmdSecretHistory,
+				CmdSecretRestore,
 			},
 		},
 		{
@@ -878,9 +1216,43 @@ var root = &cli.Command{
 					"```bash frame=\"none\"",
 					"sst remove --target urn:pulumi:prod::www::sst:aws:Astro::Astro,urn:pulumi:prod::www::sst:aws:Bucket::Assets",
 					"```",
+					"",
+					"Resources listed in `protect` in your `sst.config.ts` are not removed unless their URNs are passed in with `--allow-destroy`.",
 				}, "\n"),
 			},
 			Flags: []cli.Flag{
+				{
+					Name: "allow-destroy",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Comma separated list of protected URNs to allow destroying",
+						Long: strings.Join([]string{
+							"Comma separated list of protected URNs that are allowed to be deleted or replaced.",
+							"",
+							"Resources listed in `protect` in your `sst.config.ts` can't be deleted or replaced unless they are passed in here.",
+							"",
+							"```bash frame=\"none\"",
+							"sst remove --allow-destroy urn:pulumi:prod::www::sst:aws:Postgres$aws:rds/instance:Instance::Database",
+							"```",
+						}, "\n"),
+					},
+				},
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, text or json",
+						Long: strings.Join([]string{
+							"Set the output format. Defaults to `text`.",
+							"",
+							"With `json`, every event is printed to stdout as a line of JSON instead. Each line has a `version`, `type`, `timestamp`, and `data` field. The last line is always a `summary` with the status, the resources that changed, and any errors.",
+							"",
+							"```bash frame=\"none\"",
+							"sst remove --format json",
+							"```",
+						}, "\n"),
+					},
+				},
 				{
 					Name: "target",
 					Type: "string",
@@ -902,6 +1274,14 @@ var root = &cli.Command{
 					"However, if something unexpectedly kills the `sst deploy` process, or if you manage to run `sst deploy` concurrently, the lock might not be released.",
 					"",
 					"This should not usually happen, but it can prevent you from deploying. You can run `sst unlock` to release the lock.",
+					"",
+					"While a command is running, it periodically renews the lock. If the process is killed, the lock becomes stale after 10 minutes and the next command takes it over automatically.",
+					"",
+					"You can change this with the `SST_LOCK_TTL` environment variable. Set it to `0` to disable it.",
+					"",
+					"```bash frame=\"none\"",
+					"SST_LOCK_TTL=30m sst deploy",
+					"```",
 				}, "\n"),
 			},
 			Run: func(c *cli.Cli) error {
@@ -997,16 +1377,120 @@ var root = &cli.Command{
 			},
 		},
 		{
-			Name:   "common-errors",
-			Hidden: true,
-			Run: func(cli *cli.Cli) error {
-				data, err := json.MarshalIndent(project.CommonErrors, "", "  ")
-				if err != nil {
-					return err
-				}
-				fmt.Println(string(data))
-				return nil
+			Name: "graph",
+			Description: cli.Description{
+				Short: "Export the resources in your app as a graph",
+				Long: strings.Join([]string{
+					"Exports the resources that were deployed to the stage as a graph, along with how they are connected.",
+					"",
+					"```bash frame=\"none\"",
+					"sst graph --stage production > graph.mmd",
+					"```",
+					"",
+					"The graph includes three kinds of edges:",
+					"- A component to the resources it's made of.",
+					"- A resource to the resources it depends on.",
+					"- A function to the resources that are linked to it.",
+					"",
+					":::note",
+					"Links are only shown for functions. Other components that take a `link`, like containers or sites, don't record it in the state, so the graph doesn't include every link.",
+					":::",
+					"",
+					"Optionally, only include a single component, along with the resources it's directly connected to. This is useful to check what's affected by changing it.",
+					"",
+					"```bash frame=\"none\"",
+					"sst graph --component MyBucket --format dot | dot -Tsvg > graph.svg",
+					"```",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, mermaid, dot, or json",
+						Long:  "Set the output format. Defaults to `mermaid`, which can be embedded in markdown. Use `dot` for Graphviz or `json` for the raw nodes and edges.",
+					},
+				},
+				{
+					Name: "component",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Only include this component",
+						Long:  "The name of the component to include, like `MyBucket`. The resources it's directly connected to are included as well.",
+					},
+				},
+			},
+			Run: CmdGraph,
+		},
+		{
+			Name: "report",
+			Description: cli.Description{
+				Short: "Show how long the last deploy took",
+				Long: strings.Join([]string{
+					"Shows a timing report for the last `sst deploy` of the stage.",
+					"",
+					"```bash frame=\"none\"",
+					"sst report --stage production",
+					"```",
+					"",
+					"It lists the slowest resources, the total wall time compared to the time all the resources took added up, and the critical path. The critical path is the chain of resources that finished last, following the dependencies and the parent each one waited on. Speeding up any other resource won't make the deploy faster.",
+					"",
+					"The report is saved to `.sst/report/<stage>.json` after every deploy. It's not kept in `.sst/log/` since that is cleared every time a command runs.",
+				}, "\n"),
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, text or json",
+						Long:  "Set the output format. Defaults to `text`. With `json`, it prints the full report with the start and end time of every resource.",
+					},
+				},
 			},
+			Run: CmdReport,
+		},
+		{
+			Name: "common-errors",
+			Description: cli.Description{
+				Short: "List common errors and how to fix them",
+				Long: strings.Join([]string{
+					"Lists the common errors SST knows about and how to fix them.",
+					"",
+					"When one of these errors happens during a deploy, the CLI shows the fix under the error.",
+					"",
+					"```bash frame=\"none\"",
+					"sst common-errors",
+					"```",
+					"",
+					"Optionally, pass in an error code to see the full description.",
+					"",
+					"```bash frame=\"none\"",
+					"sst common-errors TooManyCacheBehaviors",
+					"```",
+				}, "\n"),
+			},
+			Args: []cli.Argument{
+				{
+					Name: "code",
+					Description: cli.Description{
+						Short: "The code of the error",
+						Long:  "The code of the error to show the full description of.",
+					},
+				},
+			},
+			Flags: []cli.Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, text or json",
+						Long:  "Set the output format. Defaults to `text`.",
+					},
+				},
+			},
+			Run: CmdCommonErrors,
 		},
 		{
 			Name: "refresh",
@@ -1034,6 +1518,22 @@ var root = &cli.Command{
 				}, "\n"),
 			},
 			Flags: []cli.Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: cli.Description{
+						Short: "Output format, text or json",
+						Long: strings.Join([]string{
+							"Set the output format. Defaults to `text`.",
+							"",
+							"With `json`, every event is printed to stdout as a line of JSON instead. Each line has a `version`, `type`, `timestamp`, and `data` field. The last line is always a `summary` with the status, the resources that changed, and any errors.",
+							"",
+							"```bash frame=\"none\"",
+							"sst refresh --format json",
+							"```",
+						}, "\n"),
+					},
+				},
 				{
 					Name: "target",
 					Type: "string",
@@ -1102,6 +1602,12 @@ var root = &cli.Command{
 						return p.PushState(parsed.UpdateID)
 					},
 				},
+				CmdStateCopy,
+				CmdStateHistory,
+				CmdStateShow,
+				CmdStateRollback,
+				CmdStateGc,
+				CmdStateRotatePassphrase,
 			},
 		},
 		CmdCert,
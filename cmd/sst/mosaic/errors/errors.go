@@ -7,6 +7,7 @@ import (
 	"github.com/sst/ion/cmd/sst/mosaic/aws"
 	"github.com/sst/ion/cmd/sst/mosaic/aws/appsync"
 	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/global"
 	"github.com/sst/ion/pkg/project"
 	"github.com/sst/ion/pkg/project/provider"
 	"github.com/sst/ion/pkg/server"
@@ -25,12 +26,37 @@ var transformers = []ErrorTransformer{
 	exact(aws.ErrIoTDelay, "This aws account has not had iot initialized in it before which sst depends on. It may take a few minutes before it is ready."),
 	exact(project.ErrStackRunFailed, ""),
 	exact(provider.ErrLockExists, ""),
+	exact(provider.ErrLockLost, "The lock was released or taken over by another update while this one was running, so the state was not saved. Run `sst refresh` before trying again."),
 	exact(project.ErrVersionInvalid, "The version range defined in the config is invalid"),
 	exact(provider.ErrCloudflareMissingAccount, "The Cloudflare Account ID was not able to be determined from this token. Make sure it has permissions to fetch account information or you can set the CLOUDFLARE_DEFAULT_ACCOUNT_ID environment variable to the account id you want to use."),
 	exact(server.ErrServerNotFound, "Could not find an `sst dev` session to connect to. Since you are running a command outside of the multiplexer be sure to start `sst dev` first."),
 	exact(provider.ErrBucketMissing, "The state bucket is missing, it may have been accidentally deleted. Go to https://console.aws.amazon.com/systems-manager/parameters/%252Fsst%252Fbootstrap/description?tab=Table and check if the state bucket mentioned there exists. If it doesn't you can recreate it or delete the `/sst/bootstrap` key to force recreation."),
 	exact(project.ErrBuildFailed, project.ErrBuildFailed.Error()),
 	exact(project.ErrVersionMismatch, project.ErrVersionMismatch.Error()),
+	match(func(err *project.LockMismatchError) error {
+		return util.NewReadableError(err, err.Error()+"\n\nRun `sst install` without --frozen or SST_FROZEN to update the lock.")
+	}),
+	func(err error) (bool, error) {
+		if !errors.Is(err, project.ErrProviderIntegrity) {
+			return false, nil
+		}
+		return true, util.NewReadableError(err, err.Error()+"\n\nThe package does not match the lock, it may have been tampered with.")
+	},
+	match(func(err *project.ProtectedResourceError) error {
+		return util.NewReadableError(err, err.Error()+"\n\nIf this is intended, pass the URNs in with --allow-destroy.")
+	}),
+	func(err error) (bool, error) {
+		if !errors.Is(err, global.ErrChecksumMismatch) && !errors.Is(err, global.ErrChecksumNotFound) {
+			return false, nil
+		}
+		return true, util.NewReadableError(err, err.Error()+"\n\nNothing was installed. The download could not be verified, try again and if it keeps failing the file may have been tampered with.")
+	},
+	func(err error) (bool, error) {
+		if !errors.Is(err, global.ErrBundleMismatch) && !errors.Is(err, project.ErrBundleVersion) {
+			return false, nil
+		}
+		return true, util.NewReadableError(err, err.Error()+"\n\nCreate the bundle again with `sst install --bundle` using the same version of sst, on the same OS and architecture.")
+	},
 	func(err error) (bool, error) {
 		msg := err.Error()
 		if !strings.HasPrefix(msg, "aws:") {
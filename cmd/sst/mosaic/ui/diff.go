@@ -4,8 +4,22 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 )
 
+// Drifted reports if a refresh found live properties that differ from state,
+// or found that the resource no longer exists
+func Drifted(evt *apitype.ResOutputsEvent) bool {
+	switch evt.Metadata.Op {
+	case apitype.OpDelete:
+		return true
+	case apitype.OpRefresh:
+		return evt.Metadata.New == nil || len(evt.Metadata.Diffs) > 0 || len(evt.Metadata.DetailedDiff) > 0
+	}
+	return false
+}
+
 type DiffEntry struct {
 	Path string
 	Old  interface{}
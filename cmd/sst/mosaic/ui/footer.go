@@ -162,6 +162,9 @@ func (m *footer) Update(msg any) {
 		if msg.Command == "diff" {
 			m.mode = ProgressModeDiff
 		}
+		if msg.Command == "drift" {
+			m.mode = ProgressModeDrift
+		}
 		if msg.Command == "refresh" {
 			m.mode = ProgressModeRefresh
 		}
@@ -283,6 +286,9 @@ func (m *footer) View(width int) string {
 		if m.mode == ProgressModeDiff {
 			label = "Generating"
 		}
+		if m.mode == ProgressModeDrift {
+			label = "Checking"
+		}
 		if m.mode == ProgressModeRemove {
 			label = "Removing"
 		}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project"
+)
+
+// JSONVersion is bumped whenever a record changes in a way that isn't
+// backwards compatible. New record types and new fields don't bump it.
+const JSONVersion = 1
+
+// JSONRecord is a single line of output in --format=json mode
+type JSONRecord struct {
+	Version   int         `json:"version"`
+	Type      string      `json:"type"`
+	Timestamp string      `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+type JSONStackEvent struct {
+	App     string `json:"app"`
+	Stage   string `json:"stage"`
+	Command string `json:"command"`
+	Version string `json:"version"`
+}
+
+type JSONLockedEvent struct {
+	Created time.Time `json:"created"`
+	Command string    `json:"command"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+}
+
+type JSONProviderEvent struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type JSONRetryEvent struct {
+	Attempt int      `json:"attempt"`
+	URNs    []string `json:"urns"`
+	Delay   float64  `json:"delay"`
+}
+
+type JSONBuildFailedEvent struct {
+	Error string `json:"error"`
+}
+
+type JSONChange struct {
+	URN  string         `json:"urn"`
+	Type string         `json:"type"`
+	Op   apitype.OpType `json:"op"`
+}
+
+// JSONSummary is always the last record
+type JSONSummary struct {
+	App     string                 `json:"app"`
+	Stage   string                 `json:"stage"`
+	Command string                 `json:"command"`
+	Status  string                 `json:"status"`
+	Counts  map[apitype.OpType]int `json:"counts"`
+	Changes []JSONChange           `json:"changes"`
+	Errors  []project.Error        `json:"errors"`
+	Outputs map[string]interface{} `json:"outputs"`
+	Hints   map[string]string      `json:"hints"`
+}
+
+// JSON streams events as newline delimited JSON instead of rendering them
+type JSON struct {
+	out     *json.Encoder
+	lock    sync.Mutex
+	summary *JSONSummary
+	done    bool
+}
+
+func NewJSON(out io.Writer) *JSON {
+	return &JSON{
+		out: json.NewEncoder(out),
+		summary: &JSONSummary{
+			Counts:  map[apitype.OpType]int{},
+			Changes: []JSONChange{},
+			Errors:  []project.Error{},
+			Outputs: map[string]interface{}{},
+			Hints:   map[string]string{},
+		},
+	}
+}
+
+func (j *JSON) write(kind string, data interface{}) {
+	j.out.Encode(JSONRecord{
+		Version:   JSONVersion,
+		Type:      kind,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Data:      data,
+	})
+}
+
+func (j *JSON) Event(unknown interface{}) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.done {
+		return
+	}
+	switch evt := unknown.(type) {
+	case *project.StackCommandEvent:
+		j.summary.App = evt.App
+		j.summary.Stage = evt.Stage
+		j.summary.Command = evt.Command
+		j.write("stack.start", JSONStackEvent{
+			App:     evt.App,
+			Stage:   evt.Stage,
+			Command: evt.Command,
+			Version: evt.Version,
+		})
+	case *project.ConcurrentUpdateEvent:
+		j.write("stack.locked", JSONLockedEvent{
+			Created: evt.Created,
+			Command: evt.Command,
+			User:    evt.User,
+			Host:    evt.Host,
+		})
+	case *project.ProviderDownloadEvent:
+		j.write("provider.download", JSONProviderEvent{
+			Name:    evt.Name,
+			Version: evt.Version,
+		})
+	case *project.RetryEvent:
+		j.write("stack.retry", JSONRetryEvent{
+			Attempt: evt.Attempt,
+			URNs:    evt.URNs,
+			Delay:   evt.Delay.Seconds(),
+		})
+	case *project.PolicyWarningEvent:
+		j.write("policy.warning", project.Error{
+			Message: evt.Message,
+			URN:     evt.URN,
+			Help:    evt.Help,
+		})
+	case *project.BuildFailedEvent:
+		j.summary.Errors = append(j.summary.Errors, project.Error{Message: evt.Error})
+		j.write("build.failed", JSONBuildFailedEvent{Error: evt.Error})
+	case *apitype.ResourcePreEvent:
+		if slices.Contains(IGNORED_RESOURCES, evt.Metadata.Type) {
+			return
+		}
+		j.write("resource.start", evt)
+	case *apitype.ResOutputsEvent:
+		if slices.Contains(IGNORED_RESOURCES, evt.Metadata.Type) {
+			return
+		}
+		j.summary.Counts[evt.Metadata.Op]++
+		changed := evt.Metadata.Op != apitype.OpSame
+		if evt.Metadata.Op == apitype.OpRefresh {
+			changed = Drifted(evt)
+		}
+		if changed {
+			j.summary.Changes = append(j.summary.Changes, JSONChange{
+				URN:  evt.Metadata.URN,
+				Type: evt.Metadata.Type,
+				Op:   evt.Metadata.Op,
+			})
+		}
+		j.write("resource.complete", evt)
+	case *apitype.ResOpFailedEvent:
+		j.write("resource.failed", evt)
+	case *apitype.DiagnosticEvent:
+		j.write("diagnostic", evt)
+	case *apitype.SummaryEvent:
+		j.write("engine.summary", evt)
+	case *project.CompleteEvent:
+		if evt.Old {
+			return
+		}
+		j.summary.Errors = append(j.summary.Errors, evt.Errors...)
+		if evt.Outputs != nil {
+			j.summary.Outputs = evt.Outputs
+		}
+		if evt.Hints != nil {
+			j.summary.Hints = evt.Hints
+		}
+		j.summary.Status = "success"
+		if !evt.Finished {
+			j.summary.Status = "interrupted"
+		}
+		// drift exits with an error when it finds changes, so it can't
+		// report success either
+		if j.summary.Command == "drift" && len(j.summary.Changes) > 0 {
+			j.summary.Status = "drifted"
+		}
+		j.finish()
+	}
+}
+
+func (j *JSON) finish() {
+	if len(j.summary.Errors) > 0 {
+		j.summary.Status = "failed"
+	}
+	j.write("summary", j.summary)
+	j.done = true
+}
+
+// Destroy writes the summary if the command stopped before it completed
+func (j *JSON) Destroy() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.done {
+		return
+	}
+	j.summary.Status = "failed"
+	j.finish()
+}
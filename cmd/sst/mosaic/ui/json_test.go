@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project"
+)
+
+func readRecords(t *testing.T, output *bytes.Buffer) []map[string]interface{} {
+	records := []map[string]interface{}{}
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestJSONSummary(t *testing.T) {
+	var output bytes.Buffer
+	j := NewJSON(&output)
+	j.Event(&project.StackCommandEvent{App: "app", Stage: "production", Command: "deploy"})
+	j.Event(&apitype.ResOutputsEvent{Metadata: apitype.StepEventMetadata{Op: apitype.OpCreate, URN: "urn:pulumi:production::app::aws:s3/bucket:Bucket::Assets", Type: "aws:s3/bucket:Bucket"}})
+	j.Event(&apitype.ResOutputsEvent{Metadata: apitype.StepEventMetadata{Op: apitype.OpSame, Type: "pulumi:pulumi:Stack"}})
+	j.Event(&project.CompleteEvent{Finished: true})
+	j.Destroy()
+
+	records := readRecords(t, &output)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	summary := records[len(records)-1]
+	if summary["type"] != "summary" || summary["version"] != float64(JSONVersion) {
+		t.Fatalf("expected a summary record last, got %v", summary)
+	}
+	data := summary["data"].(map[string]interface{})
+	if data["status"] != "success" || data["command"] != "deploy" {
+		t.Errorf("unexpected summary %v", data)
+	}
+	if len(data["changes"].([]interface{})) != 1 {
+		t.Errorf("expected 1 change, got %v", data["changes"])
+	}
+}
+
+func TestJSONSummaryOnFailure(t *testing.T) {
+	var output bytes.Buffer
+	j := NewJSON(&output)
+	j.Event(&project.BuildFailedEvent{Error: "syntax error"})
+	j.Destroy()
+
+	records := readRecords(t, &output)
+	summary := records[len(records)-1]["data"].(map[string]interface{})
+	if summary["status"] != "failed" || len(summary["errors"].([]interface{})) != 1 {
+		t.Errorf("unexpected summary %v", summary)
+	}
+}
+
+func TestJSONSummaryDrift(t *testing.T) {
+	var output bytes.Buffer
+	j := NewJSON(&output)
+	j.Event(&project.StackCommandEvent{App: "app", Stage: "production", Command: "drift"})
+	j.Event(&apitype.ResOutputsEvent{Metadata: apitype.StepEventMetadata{
+		Op:    apitype.OpRefresh,
+		URN:   "urn:pulumi:production::app::aws:s3/bucket:Bucket::Assets",
+		Type:  "aws:s3/bucket:Bucket",
+		Diffs: []string{"acl"},
+		Old:   &apitype.StepEventStateMetadata{Outputs: map[string]interface{}{"acl": "private"}},
+		New:   &apitype.StepEventStateMetadata{Outputs: map[string]interface{}{"acl": "public-read"}},
+	}})
+	j.Event(&project.CompleteEvent{Finished: true})
+
+	records := readRecords(t, &output)
+	summary := records[len(records)-1]["data"].(map[string]interface{})
+	if summary["status"] != "drifted" {
+		t.Errorf("expected drifted, got %v", summary["status"])
+	}
+}
@@ -34,6 +34,7 @@ const (
 	ProgressModeRemove  ProgressMode = "remove"
 	ProgressModeRefresh ProgressMode = "refresh"
 	ProgressModeDiff    ProgressMode = "diff"
+	ProgressModeDrift   ProgressMode = "drift"
 )
 
 const (
@@ -190,6 +191,9 @@ func (u *UI) Event(unknown interface{}) {
 	case *project.ConcurrentUpdateEvent:
 		u.reset()
 		u.printEvent(TEXT_DANGER, "Locked", "A concurrent update was detected on the app. Run `sst unlock` to remove the lock and try again.")
+		if evt.User != "" {
+			u.printEvent(TEXT_DANGER, "", fmt.Sprintf("↳ Held by %s@%s running `sst %s` since %s", evt.User, evt.Host, evt.Command, evt.Created.Local().Format("Jan 2 15:04")))
+		}
 
 	case *deployer.DeployFailedEvent:
 		u.reset()
@@ -227,6 +231,13 @@ func (u *UI) Event(unknown interface{}) {
 				TEXT_NORMAL_BOLD.Render("  Diff"),
 			)
 		}
+		if evt.Command == "drift" {
+			u.mode = ProgressModeDrift
+			u.println(
+				TEXT_INFO_BOLD.Render("~"),
+				TEXT_NORMAL_BOLD.Render("  Drift"),
+			)
+		}
 		u.blank()
 
 	case *project.BuildFailedEvent:
@@ -347,6 +358,19 @@ func (u *UI) Event(unknown interface{}) {
 		u.printEvent(TEXT_INFO, "Info", "Downloading provider "+evt.Name+" v"+evt.Version)
 		break
 
+	case *project.RetryEvent:
+		u.printEvent(TEXT_WARNING, "Retry", fmt.Sprintf("Retrying %d failed resources in %v, attempt %d", len(evt.URNs), evt.Delay, evt.Attempt))
+		for _, urn := range evt.URNs {
+			u.printEvent(TEXT_WARNING, "", "↳ "+u.FormatURN(urn))
+		}
+
+	case *project.PolicyWarningEvent:
+		u.printEvent(TEXT_WARNING, "Warning", evt.Message)
+		u.printEvent(TEXT_WARNING, "", "↳ "+u.FormatURN(evt.URN))
+		for _, line := range evt.Help {
+			u.printEvent(TEXT_WARNING, "", "↳ "+line)
+		}
+
 	case *project.CompleteEvent:
 		if evt.Old {
 			break
@@ -376,6 +400,9 @@ func (u *UI) Event(unknown interface{}) {
 				if u.mode == ProgressModeDiff {
 					label = "Generated"
 				}
+				if u.mode == ProgressModeDrift {
+					label = "Checked"
+				}
 				u.print(TEXT_NORMAL_BOLD.Render("  " + label + "    "))
 			}
 			u.println()
@@ -4,7 +4,6 @@ import (
 	"strings"
 
 	"github.com/sst/ion/cmd/sst/cli"
-	"github.com/sst/ion/cmd/sst/mosaic/ui"
 	"github.com/sst/ion/pkg/bus"
 	"github.com/sst/ion/pkg/project"
 	"github.com/sst/ion/pkg/server"
@@ -25,10 +24,14 @@ func CmdRefresh(c *cli.Cli) error {
 
 	var wg errgroup.Group
 	defer wg.Wait()
-	ui := ui.New(c.Context)
+	ui, err := newRenderer(c)
+	if err != nil {
+		return err
+	}
 	events := bus.SubscribeAll()
 	defer close(events)
 	wg.Go(func() error {
+		defer ui.Destroy()
 		for evt := range events {
 			ui.Event(evt)
 		}
@@ -42,7 +45,6 @@ func CmdRefresh(c *cli.Cli) error {
 		defer c.Cancel()
 		return s.Start(c.Context, p)
 	})
-	defer ui.Destroy()
 	defer c.Cancel()
 	err = p.Run(c.Context, &project.StackInput{
 		Command:    "refresh",
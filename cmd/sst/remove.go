@@ -4,7 +4,6 @@ import (
 	"strings"
 
 	"github.com/sst/ion/cmd/sst/cli"
-	"github.com/sst/ion/cmd/sst/mosaic/ui"
 	"github.com/sst/ion/pkg/bus"
 	"github.com/sst/ion/pkg/project"
 	"github.com/sst/ion/pkg/server"
@@ -22,10 +21,17 @@ func CmdRemove(c *cli.Cli) error {
 	if c.String("target") != "" {
 		target = strings.Split(c.String("target"), ",")
 	}
+	allowDestroy := []string{}
+	if c.String("allow-destroy") != "" {
+		allowDestroy = strings.Split(c.String("allow-destroy"), ",")
+	}
 
 	var wg errgroup.Group
 	defer wg.Wait()
-	ui := ui.New(c.Context)
+	ui, err := newRenderer(c)
+	if err != nil {
+		return err
+	}
 	s, err := server.New()
 	if err != nil {
 		return err
@@ -37,18 +43,19 @@ func CmdRemove(c *cli.Cli) error {
 	events := bus.SubscribeAll()
 	defer close(events)
 	wg.Go(func() error {
+		defer ui.Destroy()
 		for evt := range events {
 			ui.Event(evt)
 		}
 		return nil
 	})
-	defer ui.Destroy()
 	defer c.Cancel()
 	err = p.Run(c.Context, &project.StackInput{
-		Command:    "remove",
-		Target:     target,
-		ServerPort: s.Port,
-		Verbose:    c.Bool("verbose"),
+		Command:      "remove",
+		Target:       target,
+		ServerPort:   s.Port,
+		Verbose:      c.Bool("verbose"),
+		AllowDestroy: allowDestroy,
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/sst/ion/cmd/sst/cli"
+	"github.com/sst/ion/internal/util"
+)
+
+func CmdReport(c *cli.Cli) error {
+	p, err := c.InitProject()
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	report, err := p.ReadReport()
+	if os.IsNotExist(err) {
+		return util.NewReadableError(err, "There's no report for this stage yet, run `sst deploy` first")
+	}
+	if err != nil {
+		return util.NewReadableError(err, "Could not read the report")
+	}
+
+	switch c.String("format") {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case "", "text":
+	default:
+		return util.NewReadableError(nil, "Unknown format "+c.String("format")+", use text or json")
+	}
+
+	bold := color.New(color.Bold)
+	dim := color.New(color.FgHiBlack)
+	bold.Printf("%s %s/%s", report.Command, report.App, report.Stage)
+	dim.Printf("  %s\n\n", report.Started.Local().Format("Jan 2 15:04"))
+
+	setup := time.Duration(0)
+	if len(report.Resources) > 0 {
+		setup = report.Resources[0].Started.Sub(report.Started)
+	}
+	fmt.Printf("%-16s %s\n", "Wall time", formatReportDuration(report.Wall()))
+	fmt.Printf("%-16s %s\n", "Resource time", formatReportDuration(report.Total()))
+	fmt.Printf("%-16s %s\n", "Before resources", formatReportDuration(setup))
+	fmt.Printf("%-16s %d\n", "Resources", len(report.Resources))
+	if len(report.Resources) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	bold.Println("Slowest")
+	for _, item := range report.Slowest(10) {
+		line := fmt.Sprintf("  %8s  %-8s %s", formatReportDuration(item.Duration()), item.Op, formatReportURN(item.URN, item.Parent))
+		if item.Failed {
+			color.New(color.FgRed).Println(line + "  failed")
+			continue
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println()
+	bold.Println("Critical path")
+	for _, urn := range report.CriticalPath {
+		item := report.Resource(urn)
+		if item == nil {
+			continue
+		}
+		fmt.Printf("  %8s  %s", formatReportDuration(item.Duration()), formatReportURN(item.URN, item.Parent))
+		dim.Printf("  started at +%s, waited %s on dependencies\n",
+			formatReportDuration(item.Started.Sub(report.Started)),
+			formatReportDuration(report.Waited(item)),
+		)
+	}
+	return nil
+}
+
+func formatReportDuration(duration time.Duration) string {
+	if duration < time.Second {
+		return duration.Round(time.Millisecond).String()
+	}
+	return duration.Round(100 * time.Millisecond).String()
+}
+
+func formatReportURN(urn string, parent string) string {
+	child := resource.URN(urn)
+	result := child.Name() + " " + child.Type().DisplayName()
+	if parent != "" && resource.URN(parent).Type().DisplayName() != "pulumi:pulumi:Stack" {
+		result = resource.URN(parent).Name() + " " + resource.URN(parent).Type().DisplayName() + " → " + result
+	}
+	return result
+}
@@ -2,13 +2,18 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/joho/godotenv"
 	"github.com/sst/ion/cmd/sst/cli"
 	"github.com/sst/ion/cmd/sst/mosaic/dev"
 	"github.com/sst/ion/cmd/sst/mosaic/ui"
@@ -109,9 +114,13 @@ var CmdSecretLoad = &cli.Command{
 			"",
 			"```sh title=\"secrets.env\"",
 			"KEY_1=VALUE1",
-			"KEY_2=VALUE2",
+			"export KEY_2=\"VALUE2\"",
+			"KEY_3=\"multiline",
+			"value\"",
 			"```",
 			"",
+			"Values can be quoted and span multiple lines, and lines can start with `export`. Variables like `$HOME` are expanded in double quoted and unquoted values, use single quotes or `\\$` to keep them as is. The output of `sst secret export` can be loaded back in.",
+			"",
 			"Optionally, set the secrets in a specific stage.",
 			"",
 			"```bash frame=\"none\"",
@@ -171,21 +180,20 @@ var CmdSecretLoad = &cli.Command{
 		}
 		defer file.Close()
 
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.HasPrefix(line, "#") {
-				continue
-			}
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				ui.Success(fmt.Sprintf("Setting %s", parts[0]))
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-				secrets[key] = value
-			}
+		parsed, err := godotenv.Parse(file)
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Could not parse file %s", filePath))
 		}
-		err = provider.PutSecrets(backend, p.App().Name, stage, secrets)
+		keys := make([]string, 0, len(parsed))
+		for key := range parsed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ui.Success(fmt.Sprintf("Setting %s", key))
+			secrets[key] = parsed[key]
+		}
+		err = provider.PutSecrets(backend, p.Version(), p.App().Name, stage, secrets)
 		if err != nil {
 			return util.NewReadableError(err, "Could not set secret")
 		}
@@ -345,7 +353,7 @@ var CmdSecretSet = &cli.Command{
 			return util.NewReadableError(err, "Could not get secrets")
 		}
 		secrets[key] = value
-		err = provider.PutSecrets(backend, p.App().Name, stage, secrets)
+		err = provider.PutSecrets(backend, p.Version(), p.App().Name, stage, secrets)
 		if err != nil {
 			return util.NewReadableError(err, "Could not set secret")
 		}
@@ -436,7 +444,7 @@ var CmdSecretRemove = &cli.Command{
 			return util.NewReadableError(nil, fmt.Sprintf("Secret \"%s\" does not exist", key))
 		}
 		delete(secrets, key)
-		err = provider.PutSecrets(backend, p.App().Name, stage, secrets)
+		err = provider.PutSecrets(backend, p.Version(), p.App().Name, stage, secrets)
 		if err != nil {
 			return util.NewReadableError(err, "Could not set secret")
 		}
@@ -454,3 +462,370 @@ var CmdSecretRemove = &cli.Command{
 		return nil
 	},
 }
+
+var CmdSecretHistory = &cli.Command{
+	Name: "history",
+	Description: cli.Description{
+		Short: "Show the history of a secret",
+		Long: strings.Join([]string{
+			"Show every version of a secret, along with who changed it and when.",
+			"",
+			"```bash frame=\"none\" frame=\"none\"",
+			"sst secret history StripeSecret --stage production",
+			"```",
+			"",
+			"The author is the git user that made the change. For the `aws` home, the AWS identity that made the change is shown as well.",
+			"",
+			"Pass in `--fallback` to show the history of the fallback value.",
+		}, "\n"),
+	},
+	Args: []cli.Argument{
+		{
+			Name:     "name",
+			Required: true,
+			Description: cli.Description{
+				Short: "The name of the secret",
+				Long:  "The name of the secret.",
+			},
+		},
+	},
+	Examples: []cli.Example{
+		{
+			Content: "sst secret history StripeSecret --stage production",
+			Description: cli.Description{
+				Short: "Show the history of StripeSecret in production",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		key := c.Positional(0)
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+		stage := p.App().Stage
+		if c.Bool("fallback") {
+			stage = ""
+		}
+		history, err := provider.GetSecretHistory(p.Backend(), p.App().Name, stage)
+		if err != nil {
+			return util.NewReadableError(err, "Could not get secret history")
+		}
+		versions := history[key]
+		if len(versions) == 0 {
+			return util.NewReadableError(nil, fmt.Sprintf("No history for secret \"%s\"", key))
+		}
+		for i := len(versions) - 1; i >= 0; i-- {
+			version := versions[i]
+			author := version.Author
+			if author == "" {
+				author = "unknown"
+			}
+			if version.Identity != "" {
+				author += " (" + version.Identity + ")"
+			}
+			action := "set"
+			if version.Removed {
+				action = "removed"
+			}
+			if version.Restored > 0 {
+				action = fmt.Sprintf("restored v%d", version.Restored)
+			}
+			line := fmt.Sprintf("v%-4d %-20s %-12s %s", version.Version, version.Time, action, author)
+			if version.CLIVersion != "" {
+				line += color.New(color.FgHiBlack).Sprintf("  sst %s", version.CLIVersion)
+			}
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+var CmdSecretRestore = &cli.Command{
+	Name: "restore",
+	Description: cli.Description{
+		Short: "Restore a previous version of a secret",
+		Long: strings.Join([]string{
+			"Set a secret back to the value it had in a previous version.",
+			"",
+			"```bash frame=\"none\" frame=\"none\"",
+			"sst secret restore StripeSecret --version 3 --stage production",
+			"```",
+			"",
+			"Use `sst secret history` to see the versions of a secret. The restore is recorded as a new version.",
+		}, "\n"),
+	},
+	Args: []cli.Argument{
+		{
+			Name:     "name",
+			Required: true,
+			Description: cli.Description{
+				Short: "The name of the secret",
+				Long:  "The name of the secret.",
+			},
+		},
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "version",
+			Type: "string",
+			Description: cli.Description{
+				Short: "The version to restore",
+				Long:  "The version of the secret to restore.",
+			},
+		},
+	},
+	Examples: []cli.Example{
+		{
+			Content: "sst secret restore StripeSecret --version 3",
+			Description: cli.Description{
+				Short: "Restore version 3 of StripeSecret",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		key := c.Positional(0)
+		target, err := strconv.Atoi(strings.TrimPrefix(c.String("version"), "v"))
+		if err != nil || target < 1 {
+			return util.NewReadableError(err, "Pass in the version to restore with --version")
+		}
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+		stage := p.App().Stage
+		if c.Bool("fallback") {
+			stage = ""
+		}
+		restored, err := provider.RestoreSecret(p.Backend(), p.Version(), p.App().Name, stage, key, target)
+		if err != nil {
+			if errors.Is(err, provider.ErrSecretVersionNotFound) {
+				return util.NewReadableError(err, fmt.Sprintf("Secret \"%s\" has no version %d", key, target))
+			}
+			return util.NewReadableError(err, "Could not restore secret")
+		}
+		url, _ := server.Discover(p.PathConfig(), p.App().Stage)
+		suffix := " Run \"sst deploy\" to update."
+		if url != "" {
+			suffix = ""
+			dev.Deploy(c.Context, url)
+		}
+		if restored.Removed {
+			ui.Success(fmt.Sprintf("Restored \"%s\" to v%d, which removed it.%s", key, target, suffix))
+			return nil
+		}
+		ui.Success(fmt.Sprintf("Restored \"%s\" to v%d.%s", key, target, suffix))
+		return nil
+	},
+}
+
+var CmdSecretExport = &cli.Command{
+	Name: "export",
+	Description: cli.Description{
+		Short: "Export secrets",
+		Long: strings.Join([]string{
+			"Print all the secrets of a stage in a format that can be loaded back in.",
+			"",
+			"```bash frame=\"none\" frame=\"none\"",
+			"sst secret export --stage production > prod.env",
+			"```",
+			"",
+			"Use `--format` to pick the format. It supports `dotenv`, `json`, and `shell`. The default is `dotenv`.",
+			"",
+			"```bash frame=\"none\" frame=\"none\"",
+			"eval \"$(sst secret export --format shell)\"",
+			"```",
+			"",
+			"Export the fallback secrets.",
+			"",
+			"```bash frame=\"none\" frame=\"none\"",
+			"sst secret export --fallback",
+			"```",
+		}, "\n"),
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "format",
+			Type: "string",
+			Description: cli.Description{
+				Short: "The format to export in",
+				Long:  "The format to export in. One of `dotenv`, `json`, or `shell`.",
+			},
+		},
+	},
+	Examples: []cli.Example{
+		{
+			Content: "sst secret export --stage production > prod.env",
+			Description: cli.Description{
+				Short: "Export the secrets in production to a file",
+			},
+		},
+		{
+			Content: "sst secret export --format json",
+			Description: cli.Description{
+				Short: "Export the secrets as JSON",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		format := c.String("format")
+		if format == "" {
+			format = "dotenv"
+		}
+		if format != "dotenv" && format != "json" && format != "shell" {
+			return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", use dotenv, json, or shell", format))
+		}
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+		stage := p.App().Stage
+		if c.Bool("fallback") {
+			stage = ""
+		}
+		secrets, err := provider.GetSecrets(p.Backend(), p.App().Name, stage)
+		if err != nil {
+			return util.NewReadableError(err, "Could not get secrets")
+		}
+		output, err := formatSecrets(secrets, format)
+		if err != nil {
+			return util.NewReadableError(err, "Could not export secrets: "+err.Error()+". Use --format json instead.")
+		}
+		fmt.Print(output)
+		return nil
+	},
+}
+
+func formatSecrets(secrets map[string]string, format string) (string, error) {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var output strings.Builder
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(secrets, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	case "shell":
+		for _, key := range keys {
+			value := strings.ReplaceAll(secrets[key], "'", `'\''`)
+			output.WriteString(fmt.Sprintf("export %s='%s'\n", key, value))
+		}
+		return output.String(), nil
+	}
+	for _, key := range keys {
+		value, err := quoteDotenv(secrets[key])
+		if err != nil {
+			return "", fmt.Errorf("%s %w", key, err)
+		}
+		output.WriteString(key + "=" + value + "\n")
+	}
+	return output.String(), nil
+}
+
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+
+// quoteDotenv quotes a value so godotenv reads it back unchanged. godotenv.Marshal
+// leaves numbers like 0123 unquoted, which loses the leading zero. godotenv
+// also drops a closing quote at the end of a double quoted value, so those are
+// single quoted instead.
+func quoteDotenv(value string) (string, error) {
+	if strings.HasSuffix(value, `\`) {
+		return "", fmt.Errorf("ends with a backslash, which can't be written to a .env file")
+	}
+	if !strings.HasSuffix(value, `"`) {
+		return `"` + dotenvEscaper.Replace(value) + `"`, nil
+	}
+	if strings.Contains(value, "'") {
+		return "", fmt.Errorf("ends with a double quote and has a single quote, which can't be written to a .env file")
+	}
+	return "'" + value + "'", nil
+}
+
+var CmdSecretCopy = &cli.Command{
+	Name: "copy",
+	Description: cli.Description{
+		Short: "Copy secrets to another stage",
+		Long: strings.Join([]string{
+			"Copy all the secrets from one stage to another.",
+			"",
+			"```bash frame=\"none\" frame=\"none\"",
+			"sst secret copy --from-stage staging --to-stage pr-123",
+			"```",
+			"",
+			"If `--from-stage` isn't passed in, the current stage is used. Secrets that already exist in the destination stage are overwritten, and the rest are left alone.",
+		}, "\n"),
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "from-stage",
+			Type: "string",
+			Description: cli.Description{
+				Short: "The stage to copy from",
+				Long:  "The stage to copy the secrets from.",
+			},
+		},
+		{
+			Name: "to-stage",
+			Type: "string",
+			Description: cli.Description{
+				Short: "The stage to copy to",
+				Long:  "The stage to copy the secrets to.",
+			},
+		},
+	},
+	Examples: []cli.Example{
+		{
+			Content: "sst secret copy --from-stage staging --to-stage pr-123",
+			Description: cli.Description{
+				Short: "Copy the secrets in staging to a preview stage",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		to := c.String("to-stage")
+		if to == "" {
+			return util.NewReadableError(nil, "Pass in the stage to copy to with --to-stage")
+		}
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+		from := c.String("from-stage")
+		if from == "" {
+			from = p.App().Stage
+		}
+		if from == to {
+			return util.NewReadableError(nil, "The stage to copy from and to are the same")
+		}
+		backend := p.Backend()
+		secrets, err := provider.GetSecrets(backend, p.App().Name, from)
+		if err != nil {
+			return util.NewReadableError(err, "Could not get secrets")
+		}
+		if len(secrets) == 0 {
+			return util.NewReadableError(nil, fmt.Sprintf("No secrets found in stage \"%s\"", from))
+		}
+		existing, err := provider.GetSecrets(backend, p.App().Name, to)
+		if err != nil {
+			return util.NewReadableError(err, "Could not get secrets")
+		}
+		for key, value := range secrets {
+			existing[key] = value
+		}
+		err = provider.PutSecrets(backend, p.Version(), p.App().Name, to, existing)
+		if err != nil {
+			return util.NewReadableError(err, "Could not set secrets")
+		}
+		ui.Success(fmt.Sprintf("Copied %d secrets from \"%s\" to \"%s\". Run \"sst deploy --stage %s\" to update.", len(secrets), from, to, to))
+		return nil
+	},
+}
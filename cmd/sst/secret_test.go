@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/joho/godotenv"
+)
+
+func TestFormatSecretsDotenv(t *testing.T) {
+	secrets := map[string]string{
+		"ZERO":      "0123",
+		"DOLLAR":    "abc$HOME",
+		"BRACES":    "${HOME}",
+		"ESCAPED":   `\$HOME`,
+		"NEWLINE":   "first\nsecond",
+		"RETURN":    "first\r\nsecond",
+		"SINGLE":    "it's",
+		"DOUBLE":    `say "hi"`,
+		"QUOTED":    `"quoted"`,
+		"BACKSLASH": `C:\path\n`,
+		"SPACES":    "  padded  ",
+		"HASH":      "value # not a comment",
+		"EMPTY":     "",
+	}
+	output, err := formatSecrets(secrets, "dotenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := godotenv.Unmarshal(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(secrets) {
+		t.Fatalf("expected %d secrets, got %d", len(secrets), len(parsed))
+	}
+	for key, value := range secrets {
+		if parsed[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, parsed[key])
+		}
+	}
+
+	for _, value := range []string{`C:\`, `it's "quoted"`} {
+		_, err := formatSecrets(map[string]string{"KEY": value}, "dotenv")
+		if err == nil {
+			t.Errorf("expected %q to fail instead of changing when loaded", value)
+		}
+	}
+}
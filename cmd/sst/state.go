@@ -1,21 +1,478 @@
 package main
 
-import "github.com/sst/ion/cmd/sst/cli"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/cli"
+	"github.com/sst/ion/cmd/sst/mosaic/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/id"
+	"github.com/sst/ion/pkg/project/provider"
+	"golang.org/x/sync/errgroup"
+)
 
 var CmdStateCopy = &cli.Command{
 	Name: "copy",
 	Description: cli.Description{
 		Short: "Copy state from one home to another.",
+		Long: strings.Join([]string{
+			"Copy the state of a stage from the current home to another one.",
+			"",
+			"```bash frame=\"none\"",
+			"sst state copy aws --stage production",
+			"```",
+			"",
+			"This copies the app state, secrets, snapshots, summaries, and update history.",
+			"The fallback secrets of the app are copied too, except the ones that are already set in the destination home.",
+			"The secrets, and the secret values in the state and snapshots, are re-encrypted with the passphrase of the destination home.",
+			"",
+			"Once it's done, update the `home` in your `sst.config.ts` to point to the new home.",
+		}, "\n"),
 	},
 	Args: []cli.Argument{
 		{
-			Name: "to",
+			Name:     "to",
+			Required: true,
 			Description: cli.Description{
 				Short: "The destination home",
+				Long:  "The destination home. One of `local`, `aws`, `cloudflare`, or `s3`.",
 			},
 		},
 	},
-	Run: func(cli *cli.Cli) error {
+	Run: func(c *cli.Cli) error {
+		to := c.Positional(0)
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+
+		if to == p.App().Home {
+			return util.NewReadableError(nil, fmt.Sprintf("The state is already stored in \"%s\"", to))
+		}
+
+		updateID := id.Descending()
+		err = p.Lock(updateID, "copy")
+		if err != nil {
+			return util.NewReadableError(err, "Could not lock state")
+		}
+		defer p.Unlock()
+
+		dest, err := p.NewHome(to)
+		if err != nil {
+			return err
+		}
+
+		err = provider.Lock(dest, updateID, p.Version(), "copy", p.App().Name, p.App().Stage)
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Could not lock the state in \"%s\"", to))
+		}
+		defer provider.Unlock(dest, p.App().Name, p.App().Stage)
+
+		skipped, err := provider.Copy(p.Backend(), dest, p.App().Name, p.App().Stage)
+		if err != nil {
+			return util.NewReadableError(err, "Could not copy state")
+		}
+
+		color.New(color.FgGreen, color.Bold).Print("✓ ")
+		color.New(color.FgWhite).Print(" Copied the app state for: ")
+		color.New(color.FgWhite, color.Bold).Println(p.App().Name, "/", p.App().Stage, "to", to)
+		fmt.Println("   " + ui.TEXT_DIM.Render(fmt.Sprintf("Set home: \"%s\" in your config to use it.", to)))
+		if len(skipped) > 0 {
+			fmt.Println("   " + ui.TEXT_DIM.Render(fmt.Sprintf("Skipped fallback secrets that are set to a different value in \"%s\": %s", to, strings.Join(skipped, ", "))))
+		}
+		return nil
+	},
+}
+
+var CmdStateHistory = &cli.Command{
+	Name: "history",
+	Description: cli.Description{
+		Short: "List the past updates of a stage",
+		Long: strings.Join([]string{
+			"List the past updates of a stage, newest first, along with a summary of what changed.",
+			"",
+			"```bash frame=\"none\"",
+			"sst state history --stage production",
+			"```",
+			"",
+			"By default, it shows the last 20 updates. Use `--limit` to change this.",
+			"",
+			"The IDs can be passed to `sst state show` and `sst state rollback`.",
+		}, "\n"),
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "limit",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Number of updates to show",
+				Long:  "Number of updates to show. Defaults to 20.",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		limit := 20
+		if c.String("limit") != "" {
+			parsed, err := strconv.Atoi(c.String("limit"))
+			if err != nil || parsed < 1 {
+				return util.NewReadableError(err, "The limit must be a positive number")
+			}
+			limit = parsed
+		}
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+
+		backend := p.Backend()
+		app, stage := p.App().Name, p.App().Stage
+		ids, err := provider.ListUpdates(backend, app, stage)
+		if err != nil {
+			return util.NewReadableError(err, "Could not list updates")
+		}
+		if len(ids) == 0 {
+			return util.NewReadableError(nil, "No updates found")
+		}
+		if len(ids) > limit {
+			ids = ids[:limit]
+		}
+
+		updates := make([]*provider.Update, len(ids))
+		summaries := make([]*provider.Summary, len(ids))
+		var wg errgroup.Group
+		wg.SetLimit(10)
+		for i, updateID := range ids {
+			i, updateID := i, updateID
+			wg.Go(func() error {
+				update, err := provider.GetUpdate(backend, app, stage, updateID)
+				if err != nil {
+					return err
+				}
+				summary, err := provider.GetSummary(backend, app, stage, updateID)
+				if err != nil {
+					return err
+				}
+				updates[i] = update
+				summaries[i] = summary
+				return nil
+			})
+		}
+		if err := wg.Wait(); err != nil {
+			return util.NewReadableError(err, "Could not get updates")
+		}
+
+		for i, updateID := range ids {
+			update := updates[i]
+			summary := summaries[i]
+			if update == nil {
+				continue
+			}
+			fmt.Println(
+				ui.TEXT_HIGHLIGHT_BOLD.Render(updateID),
+				ui.TEXT_NORMAL_BOLD.Render(update.Command),
+				ui.TEXT_DIM.Render(formatUpdateTime(update.TimeStarted, update.TimeCompleted)),
+			)
+			if summary != nil {
+				fmt.Println(
+					"  ",
+					ui.TEXT_SUCCESS.Render(fmt.Sprintf("+ %d created", summary.ResourceCreated)),
+					ui.TEXT_WARNING.Render(fmt.Sprintf("* %d updated", summary.ResourceUpdated)),
+					ui.TEXT_DANGER.Render(fmt.Sprintf("- %d deleted", summary.ResourceDeleted)),
+					ui.TEXT_DIM.Render(fmt.Sprintf("%d same", summary.ResourceSame)),
+				)
+			}
+			errs := update.Errors
+			if summary != nil && len(summary.Errors) > 0 {
+				errs = summary.Errors
+			}
+			for _, item := range errs {
+				fmt.Println("  ", ui.TEXT_DANGER.Render("✕ "+item.URN), ui.TEXT_DIM.Render(strings.TrimSpace(item.Message)))
+			}
+			if update.TimeCompleted == "" {
+				fmt.Println("  ", ui.TEXT_DIM.Render("Did not complete"))
+			}
+		}
+		return nil
+	},
+}
+
+func formatUpdateTime(started, completed string) string {
+	start, err := time.Parse(time.RFC3339, started)
+	if err != nil {
+		return started
+	}
+	result := start.Local().Format("2006-01-02 15:04:05")
+	end, err := time.Parse(time.RFC3339, completed)
+	if err != nil {
+		return result
+	}
+	return result + " (" + end.Sub(start).Round(time.Second).String() + ")"
+}
+
+var CmdStateShow = &cli.Command{
+	Name: "show",
+	Description: cli.Description{
+		Short: "Print the state snapshot of an update",
+		Long: strings.Join([]string{
+			"Print the state as it was after the given update.",
+			"",
+			"```bash frame=\"none\"",
+			"sst state show <update-id> --stage production",
+			"```",
+			"",
+			"You can find the update IDs with `sst state history`.",
+		}, "\n"),
+	},
+	Args: []cli.Argument{
+		{
+			Name:     "id",
+			Required: true,
+			Description: cli.Description{
+				Short: "The ID of the update",
+				Long:  "The ID of the update.",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		updateID := c.Positional(0)
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+
+		err = provider.PullSnapshot(p.Backend(), p.App().Name, p.App().Stage, updateID, os.Stdout)
+		if err != nil {
+			if errors.Is(err, provider.ErrSnapshotNotFound) {
+				return util.NewReadableError(err, fmt.Sprintf("No snapshot found for update \"%s\"", updateID))
+			}
+			return util.NewReadableError(err, "Could not get snapshot")
+		}
+		return nil
+	},
+}
+
+var CmdStateRollback = &cli.Command{
+	Name: "rollback",
+	Description: cli.Description{
+		Short: "Restore the state from a past update",
+		Long: strings.Join([]string{
+			"Restore the state snapshot of a past update as the current state.",
+			"",
+			"```bash frame=\"none\"",
+			"sst state rollback <update-id> --stage production",
+			"```",
+			"",
+			"This only changes the state, not the resources in your cloud provider. Run `sst refresh` or `sst deploy` afterwards to bring them back in sync.",
+			"",
+			"The rollback itself is recorded as a new update so it can be undone.",
+		}, "\n"),
+	},
+	Args: []cli.Argument{
+		{
+			Name:     "id",
+			Required: true,
+			Description: cli.Description{
+				Short: "The ID of the update",
+				Long:  "The ID of the update.",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) (err error) {
+		target := c.Positional(0)
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+
+		var parsed provider.Summary
+		parsed.Version = version
+		parsed.Command = "rollback"
+		parsed.UpdateID = id.Descending()
+		parsed.TimeStarted = time.Now().UTC().Format(time.RFC3339)
+		err = p.Lock(parsed.UpdateID, "rollback")
+		if err != nil {
+			return util.NewReadableError(err, "Could not lock state")
+		}
+		defer p.Unlock()
+		defer func() {
+			provider.PutRollback(p.Backend(), p.App().Name, p.App().Stage, parsed, err)
+		}()
+
+		_, err = p.PullSnapshot(target)
+		if err != nil {
+			if errors.Is(err, provider.ErrSnapshotNotFound) {
+				return util.NewReadableError(err, fmt.Sprintf("No snapshot found for update \"%s\"", target))
+			}
+			return util.NewReadableError(err, "Could not pull snapshot")
+		}
+		err = p.PushState(parsed.UpdateID)
+		if err != nil {
+			return util.NewReadableError(err, "Could not push state")
+		}
+		ui.Success(fmt.Sprintf("Restored the state from update \"%s\". Run \"sst refresh\" to sync it with your resources.", target))
+		return nil
+	},
+}
+
+var CmdStateGc = &cli.Command{
+	Name: "gc",
+	Description: cli.Description{
+		Short: "Remove old snapshots and update history",
+		Long: strings.Join([]string{
+			"Every update stores a snapshot of the state, a summary, and an update record. These are never removed automatically.",
+			"",
+			"This command removes the ones that fall outside the given retention policy.",
+			"",
+			"```bash frame=\"none\"",
+			"sst state gc --keep 50 --stage production",
+			"```",
+			"",
+			"Or keep everything newer than a given duration.",
+			"",
+			"```bash frame=\"none\"",
+			"sst state gc --max-age 30d --stage production",
+			"```",
+			"",
+			"When both are passed in, an update is kept if it matches either of them. Only updates that stored a snapshot are counted and the latest one is always kept. Records without a snapshot, like the ones of failed deploys or of this command, are removed once they're older than the oldest snapshot that's kept.",
+			"",
+			"Use `--dry-run` to see what would be removed.",
+		}, "\n"),
+	},
+	Flags: []cli.Flag{
+		{
+			Name: "keep",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Number of updates to keep",
+				Long:  "Number of most recent updates with a snapshot to keep.",
+			},
+		},
+		{
+			Name: "max-age",
+			Type: "string",
+			Description: cli.Description{
+				Short: "Keep updates newer than this",
+				Long:  "Keep updates newer than this duration. For example, `72h` or `30d`.",
+			},
+		},
+		{
+			Name: "dry-run",
+			Type: "bool",
+			Description: cli.Description{
+				Short: "Only print what would be removed",
+				Long:  "Only print what would be removed.",
+			},
+		},
+	},
+	Run: func(c *cli.Cli) error {
+		var policy provider.RetentionPolicy
+		if c.String("keep") == "" && c.String("max-age") == "" {
+			return util.NewReadableError(nil, "Pass in --keep or --max-age to set what to keep")
+		}
+		if c.String("keep") != "" {
+			keep, err := strconv.Atoi(c.String("keep"))
+			if err != nil || keep < 1 {
+				return util.NewReadableError(err, "The number of updates to keep must be a positive number")
+			}
+			policy.Keep = keep
+		}
+		if c.String("max-age") != "" {
+			maxAge, err := parseRetention(c.String("max-age"))
+			if err != nil {
+				return util.NewReadableError(err, "Could not parse --max-age, use a duration like 72h or 30d")
+			}
+			policy.MaxAge = maxAge
+		}
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+
+		dryRun := c.Bool("dry-run")
+		if !dryRun {
+			err = p.Lock(id.Descending(), "gc")
+			if err != nil {
+				return util.NewReadableError(err, "Could not lock state")
+			}
+			defer p.Unlock()
+		}
+
+		removed, err := provider.Prune(p.Backend(), p.App().Name, p.App().Stage, policy, dryRun)
+		if err != nil {
+			return util.NewReadableError(err, "Could not prune state")
+		}
+		if dryRun {
+			for _, item := range removed {
+				fmt.Println(item)
+			}
+			ui.Success(fmt.Sprintf("Would remove %d updates", len(removed)))
+			return nil
+		}
+		ui.Success(fmt.Sprintf("Removed %d updates", len(removed)))
+		return nil
+	},
+}
+
+// parseRetention is time.ParseDuration with support for days
+func parseRetention(input string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(input, "d"); ok {
+		parsed, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(parsed) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(input)
+}
+
+var CmdStateRotatePassphrase = &cli.Command{
+	Name: "rotate-passphrase",
+	Description: cli.Description{
+		Short: "Rotate the passphrase of a stage.",
+		Long: strings.Join([]string{
+			"Generate a new passphrase for a stage and re-encrypt its secrets, state and snapshots with it.",
+			"",
+			"```bash frame=\"none\"",
+			"sst state rotate-passphrase --stage production",
+			"```",
+			"",
+			"The old passphrase keeps working until everything has been re-encrypted. If the rotation is interrupted, run the command again to pick up where it left off.",
+			"",
+			"This isn't supported for the `s3` home when the passphrase is set through `SST_HOME_S3_PASSPHRASE`.",
+		}, "\n"),
+	},
+	Run: func(c *cli.Cli) error {
+		p, err := c.InitProject()
+		if err != nil {
+			return err
+		}
+		defer p.Cleanup()
+
+		err = p.Lock(id.Descending(), "rotate-passphrase")
+		if err != nil {
+			return util.NewReadableError(err, "Could not lock state")
+		}
+		defer p.Unlock()
+
+		err = provider.RotatePassphrase(p.Backend(), p.App().Name, p.App().Stage)
+		if err != nil {
+			if errors.Is(err, provider.ErrPassphraseFromEnv) {
+				return util.NewReadableError(err, "The passphrase is set through SST_HOME_S3_PASSPHRASE, change it there instead")
+			}
+			return util.NewReadableError(err, "Could not rotate passphrase")
+		}
+		ui.Success("Rotated passphrase")
 		return nil
 	},
 }
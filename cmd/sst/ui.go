@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"os"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/sst/ion/cmd/sst/cli"
@@ -12,6 +13,7 @@ import (
 	"github.com/sst/ion/cmd/sst/mosaic/dev"
 	"github.com/sst/ion/cmd/sst/mosaic/ui"
 	"github.com/sst/ion/cmd/sst/mosaic/ui/common"
+	"github.com/sst/ion/internal/util"
 	"github.com/sst/ion/pkg/project"
 	"github.com/sst/ion/pkg/server"
 )
@@ -90,3 +92,20 @@ func CmdUI(c *cli.Cli) error {
 		}
 	}
 }
+
+type renderer interface {
+	Event(unknown interface{})
+	Destroy()
+}
+
+// newRenderer returns the terminal ui, or streams events to stdout as
+// newline delimited json when --format=json is passed in
+func newRenderer(c *cli.Cli) (renderer, error) {
+	switch c.String("format") {
+	case "", "text":
+		return ui.New(c.Context), nil
+	case "json":
+		return ui.NewJSON(os.Stdout), nil
+	}
+	return nil, util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", use text or json", c.String("format")))
+}
@@ -5,10 +5,25 @@ import (
 )
 
 var SST_LOG = os.Getenv("SST_LOG")
+var SST_CONFIG = os.Getenv("SST_CONFIG")
 var SST_PRINT_LOGS = os.Getenv("SST_PRINT_LOGS") != ""
 var SST_NO_CLEANUP = os.Getenv("SST_NO_CLEANUP") != ""
+var SST_NO_CONFIG_CACHE = os.Getenv("SST_NO_CONFIG_CACHE") != ""
 var SST_PASSPHRASE = os.Getenv("SST_PASSPHRASE")
+var SST_FROZEN = os.Getenv("SST_FROZEN") != ""
+var SST_OFFLINE = os.Getenv("SST_OFFLINE")
 var SST_PULUMI_PATH = os.Getenv("SST_PULUMI_PATH")
+var SST_HOME_S3_ENDPOINT = os.Getenv("SST_HOME_S3_ENDPOINT")
+var SST_HOME_S3_BUCKET = os.Getenv("SST_HOME_S3_BUCKET")
+var SST_HOME_S3_REGION = os.Getenv("SST_HOME_S3_REGION")
+var SST_HOME_S3_ACCESS_KEY_ID = os.Getenv("SST_HOME_S3_ACCESS_KEY_ID")
+var SST_HOME_S3_SECRET_ACCESS_KEY = os.Getenv("SST_HOME_S3_SECRET_ACCESS_KEY")
+var SST_HOME_S3_PASSPHRASE = os.Getenv("SST_HOME_S3_PASSPHRASE")
+var SST_LOCK_TTL = os.Getenv("SST_LOCK_TTL")
+var SST_KEY_PROVIDER = os.Getenv("SST_KEY_PROVIDER")
+var SST_KMS_KEY_ID = os.Getenv("SST_KMS_KEY_ID")
+var SST_KMS_ENDPOINT = os.Getenv("SST_KMS_ENDPOINT")
+var SST_KEYFILE = os.Getenv("SST_KEYFILE")
 // SST_BUILD_CONCURRENCY is deprecated, use SST_FUNCTION_BUILD_CONCURRENCY instead
 var SST_BUILD_CONCURRENCY = os.Getenv("SST_BUILD_CONCURRENCY")
 var SST_BUILD_CONCURRENCY_FUNCTION = os.Getenv("SST_BUILD_CONCURRENCY_FUNCTION")
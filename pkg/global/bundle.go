@@ -0,0 +1,482 @@
+package global
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sst/ion/pkg/flag"
+	"github.com/sst/ion/pkg/process"
+)
+
+// Bundle is the manifest at the root of an offline bundle. A bundle has
+// everything sst would otherwise download: the pulumi cli, bun, the pulumi
+// plugins and the installed providers.
+//
+//	bundle.json
+//	bin/pulumi, bin/pulumi-language-nodejs, ..., bin/bun
+//	plugins/resource-aws-v6.52.0/...
+//	platform/provider-lock.json
+//	platform/node_modules/...
+type Bundle struct {
+	Version string   `json:"version"`
+	OS      string   `json:"os"`
+	Arch    string   `json:"arch"`
+	Pulumi  string   `json:"pulumi"`
+	Bun     string   `json:"bun,omitempty"`
+	Plugins []string `json:"plugins"`
+}
+
+var ErrBundleMismatch = fmt.Errorf("offline bundle does not match")
+
+// Offline is true when everything has to be installed from the bundle set
+// with SST_OFFLINE
+func Offline() bool {
+	return flag.SST_OFFLINE != ""
+}
+
+var offlineDir = sync.OnceValues(func() (string, error) {
+	path, err := filepath.Abs(flag.SST_OFFLINE)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return path, nil
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano())))
+	dir := filepath.Join(configDir, "offline", hex.EncodeToString(hash[:8]))
+	if _, err := os.Stat(filepath.Join(dir, "bundle.json")); err == nil {
+		return dir, nil
+	}
+	slog.Info("extracting offline bundle", "path", path, "dir", dir)
+	tmp := dir + ".tmp"
+	os.RemoveAll(tmp)
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return "", err
+	}
+	defer gzr.Close()
+	err = extract(gzr, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	os.RemoveAll(dir)
+	return dir, os.Rename(tmp, dir)
+})
+
+// OfflineDir returns the directory of the offline bundle, extracting it
+// first if it's a tarball
+func OfflineDir() (string, error) {
+	return offlineDir()
+}
+
+// ReadBundle reads the manifest of the offline bundle and checks that it was
+// made for this platform and these versions of pulumi and bun
+func ReadBundle() (*Bundle, error) {
+	dir, err := OfflineDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "bundle.json"))
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	err = json.Unmarshal(data, &bundle)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.OS != runtime.GOOS || bundle.Arch != runtime.GOARCH {
+		return nil, fmt.Errorf("%w: made for %s-%s", ErrBundleMismatch, bundle.OS, bundle.Arch)
+	}
+	if bundle.Pulumi != PULUMI_VERSION {
+		return nil, fmt.Errorf("%w: has pulumi %s instead of %s", ErrBundleMismatch, bundle.Pulumi, PULUMI_VERSION)
+	}
+	if !flag.NO_BUN && bundle.Bun != BUN_VERSION {
+		return nil, fmt.Errorf("%w: has bun %s instead of %s", ErrBundleMismatch, bundle.Bun, BUN_VERSION)
+	}
+	return &bundle, nil
+}
+
+// installPulumiOffline copies the pulumi cli and the plugins from the bundle
+func installPulumiOffline() error {
+	slog.Info("pulumi install offline")
+	bundle, err := ReadBundle()
+	if err != nil {
+		return err
+	}
+	dir, err := OfflineDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "bin"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "pulumi") {
+			continue
+		}
+		err = copyFile(filepath.Join(dir, "bin", entry.Name()), filepath.Join(BinPath(), entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	for _, plugin := range bundle.Plugins {
+		target := filepath.Join(PluginPath(), plugin)
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		err = CopyDir(filepath.Join(dir, "plugins", plugin), target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingPlugins is true if any plugin in the bundle isn't in the plugin
+// cache yet
+func missingPlugins() bool {
+	bundle, err := ReadBundle()
+	if err != nil {
+		return true
+	}
+	for _, plugin := range bundle.Plugins {
+		if _, err := os.Stat(filepath.Join(PluginPath(), plugin)); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func installBunOffline() error {
+	slog.Info("bun install offline")
+	_, err := ReadBundle()
+	if err != nil {
+		return err
+	}
+	dir, err := OfflineDir()
+	if err != nil {
+		return err
+	}
+	return copyFile(filepath.Join(dir, "bin", "bun"), BunPath())
+}
+
+func PluginPath() string {
+	return filepath.Join(configDir, "plugins")
+}
+
+// InstallPlugin makes sure a pulumi resource plugin is in the plugin cache
+// and returns the name of its directory
+func InstallPlugin(name string, version string, server string) (string, error) {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	dir := "resource-" + name + "-" + version
+	if _, err := os.Stat(filepath.Join(PluginPath(), dir)); err == nil {
+		return dir, nil
+	}
+	slog.Info("installing plugin", "name", name, "version", version)
+	args := []string{"plugin", "install", "resource", name, version}
+	if server != "" {
+		args = append(args, "--server", server)
+	}
+	cmd := process.Command(PulumiPath(), args...)
+	cmd.Env = append(os.Environ(), "PULUMI_HOME="+configDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to install plugin %s %s: %w\n%s", name, version, err, output)
+	}
+	return dir, nil
+}
+
+// BundleWriter writes an offline bundle as a gzipped tarball
+type BundleWriter struct {
+	file *os.File
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func NewBundleWriter(path string) (*BundleWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	gzw := gzip.NewWriter(file)
+	return &BundleWriter{
+		file: file,
+		gzip: gzw,
+		tar:  tar.NewWriter(gzw),
+	}, nil
+}
+
+// AddTools adds the manifest, the pulumi cli, bun and the given plugins
+func (b *BundleWriter) AddTools(version string, plugins []string) error {
+	entries, err := os.ReadDir(BinPath())
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "pulumi") {
+			continue
+		}
+		err = b.AddFile(filepath.Join(BinPath(), entry.Name()), "bin/"+entry.Name())
+		if err != nil {
+			return err
+		}
+	}
+	bundle := Bundle{
+		Version: version,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Pulumi:  PULUMI_VERSION,
+		Plugins: plugins,
+	}
+	if !flag.NO_BUN {
+		bundle.Bun = BUN_VERSION
+		err = b.AddFile(BunPath(), "bin/bun")
+		if err != nil {
+			return err
+		}
+	}
+	for _, plugin := range plugins {
+		err = b.AddDir(filepath.Join(PluginPath(), plugin), "plugins/"+plugin)
+		if err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.add(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "bundle.json",
+		Mode:     0644,
+		Size:     int64(len(data)),
+	}, strings.NewReader(string(data)))
+}
+
+func (b *BundleWriter) AddFile(src string, name string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	return b.addEntry(src, name, info)
+}
+
+// AddDir adds everything in a directory, keeping symlinks as they are
+func (b *BundleWriter) AddDir(src string, name string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return b.addEntry(path, filepath.ToSlash(filepath.Join(name, rel)), info)
+	})
+}
+
+func (b *BundleWriter) addEntry(path string, name string, info fs.FileInfo) error {
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		link = target
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if !info.Mode().IsRegular() {
+		return b.add(header, nil)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return b.add(header, file)
+}
+
+func (b *BundleWriter) add(header *tar.Header, body io.Reader) error {
+	err := b.tar.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return nil
+	}
+	_, err = io.Copy(b.tar, body)
+	return err
+}
+
+func (b *BundleWriter) Close() error {
+	if err := b.tar.Close(); err != nil {
+		return err
+	}
+	if err := b.gzip.Close(); err != nil {
+		return err
+	}
+	return b.file.Close()
+}
+
+// extract unpacks a tarball keeping its directory structure, unlike untar
+func extract(reader io.Reader, target string) error {
+	root := filepath.Clean(target)
+	links := map[string]bool{}
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(root, filepath.FromSlash(header.Name))
+		if !within(root, path) {
+			return fmt.Errorf("invalid path in bundle: %s", header.Name)
+		}
+		// nothing is written through a link from the bundle, so a link can't
+		// be used to place files elsewhere
+		for dir := filepath.Dir(path); dir != root; dir = filepath.Dir(dir) {
+			if links[dir] {
+				return fmt.Errorf("invalid path in bundle: %s is inside a symlink", header.Name)
+			}
+		}
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeSymlink:
+			linkname := filepath.FromSlash(header.Linkname)
+			if filepath.IsAbs(linkname) || strings.HasPrefix(header.Linkname, "/") || !within(root, filepath.Join(filepath.Dir(path), linkname)) {
+				return fmt.Errorf("invalid symlink in bundle: %s -> %s", header.Name, header.Linkname)
+			}
+			links[path] = true
+			err = os.Symlink(header.Linkname, path)
+		case tar.TypeReg:
+			err = writeFile(path, tarReader, os.FileMode(header.Mode).Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// a link can still leave the target through another link, which only
+	// shows once they're all in place
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	for link := range links {
+		resolved, err := filepath.EvalSymlinks(link)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !within(resolvedRoot, resolved) {
+			rel, _ := filepath.Rel(root, link)
+			return fmt.Errorf("invalid symlink in bundle: %s points outside of it", filepath.ToSlash(rel))
+		}
+	}
+	return nil
+}
+
+// within checks that path is inside root, without following symlinks
+func within(root string, path string) bool {
+	return strings.HasPrefix(filepath.Clean(path), root+string(filepath.Separator))
+}
+
+// CopyDir copies a directory, keeping symlinks as they are
+func CopyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target)
+		}
+	})
+}
+
+func copyFile(src string, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return writeFile(dst, file, info.Mode().Perm())
+}
+
+// writeFile writes to a temporary file first so a running binary can be
+// replaced
+func writeFile(path string, body io.Reader, mode os.FileMode) error {
+	tmp := path + ".sst-tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, body)
+	out.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
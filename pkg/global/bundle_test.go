@@ -0,0 +1,107 @@
+package global
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "pkg", ".bin"), 0755)
+	os.WriteFile(filepath.Join(src, "pkg", "index.js"), []byte("module.exports = 1"), 0755)
+	os.Symlink("../index.js", filepath.Join(src, "pkg", ".bin", "index"))
+
+	out := filepath.Join(t.TempDir(), "bundle.tgz")
+	writer, err := NewBundleWriter(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.AddDir(src, "platform/node_modules"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := extract(gzr, dir); err != nil {
+		t.Fatal(err)
+	}
+	copied := t.TempDir()
+	if err := CopyDir(filepath.Join(dir, "platform", "node_modules"), copied); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(copied, "pkg", ".bin", "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "module.exports = 1" {
+		t.Fatalf("unexpected content %q", data)
+	}
+	link, err := os.Readlink(filepath.Join(copied, "pkg", ".bin", "index"))
+	if err != nil || link != "../index.js" {
+		t.Fatalf("expected symlink to be kept, got %q %v", link, err)
+	}
+	info, err := os.Stat(filepath.Join(copied, "pkg", "index.js"))
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Fatal("expected file mode to be kept")
+	}
+}
+
+func TestExtractSymlinks(t *testing.T) {
+	type entry struct {
+		name string
+		link string
+	}
+	archive := func(entries ...entry) *bytes.Buffer {
+		var buf bytes.Buffer
+		writer := tar.NewWriter(&buf)
+		for _, entry := range entries {
+			header := &tar.Header{Name: entry.name, Mode: 0755, Typeflag: tar.TypeDir}
+			if entry.link != "" {
+				header.Typeflag = tar.TypeSymlink
+				header.Linkname = entry.link
+			}
+			writer.WriteHeader(header)
+		}
+		writer.Close()
+		return &buf
+	}
+
+	valid := archive(entry{name: "pkg"}, entry{name: "pkg/.bin"}, entry{name: "pkg/.bin/cli", link: "../index.js"})
+	if err := extract(valid, t.TempDir()); err != nil {
+		t.Fatalf("expected a link inside the bundle to be kept, got %v", err)
+	}
+
+	invalid := map[string]*bytes.Buffer{
+		"absolute": archive(entry{name: "passwd", link: "/etc/passwd"}),
+		"outside":  archive(entry{name: "pkg"}, entry{name: "pkg/up", link: "../../outside"}),
+		"through":  archive(entry{name: "up", link: "pkg"}, entry{name: "pkg"}, entry{name: "up/dir"}),
+		"chained": archive(
+			entry{name: "a"},
+			entry{name: "p"},
+			entry{name: "p/q"},
+			entry{name: "p/q/y", link: "../../a"},
+			entry{name: "x", link: "p/q/y/../.."},
+		),
+	}
+	for name, bundle := range invalid {
+		if err := extract(bundle, t.TempDir()); err == nil {
+			t.Errorf("%s: expected the symlink to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package global
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var ErrChecksumMismatch = fmt.Errorf("checksum does not match")
+var ErrChecksumNotFound = fmt.Errorf("checksum not found")
+
+// download fetches a file to a temporary file and verifies it against the
+// expected sha256 before returning it. The caller has to remove the file.
+func download(url string, checksum string) (*os.File, error) {
+	slog.Info("downloading", "url", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: HTTP status %d", url, resp.StatusCode)
+	}
+	err = os.MkdirAll(BinPath(), 0755)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(BinPath(), ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), resp.Body)
+	if err == nil {
+		err = verify(hash.Sum(nil), checksum, url)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+func verify(sum []byte, expected string, url string) error {
+	actual := hex.EncodeToString(sum)
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, url, expected, actual)
+	}
+	slog.Info("checksum verified", "url", url, "sha256", actual)
+	return nil
+}
+
+// fetchChecksum downloads a published checksums file and returns the sum
+// for the given file
+func fetchChecksum(url string, filename string) (string, error) {
+	slog.Info("downloading checksums", "url", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download checksums %s: HTTP status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return parseChecksums(data, filename)
+}
+
+// parseChecksums reads the `<sha256>  <filename>` format that sha256sum and
+// goreleaser write
+func parseChecksums(data []byte, filename string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == filename {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("%w for %s", ErrChecksumNotFound, filename)
+}
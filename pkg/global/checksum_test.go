@@ -0,0 +1,47 @@
+package global
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksums(t *testing.T) {
+	data := []byte("abc123  pulumi-v3.136.1-linux-x64.tar.gz\ndef456 *bun-linux-aarch64.zip\n")
+	sum, err := parseChecksums(data, "bun-linux-aarch64.zip")
+	if err != nil || sum != "def456" {
+		t.Fatalf("unexpected %q %v", sum, err)
+	}
+	_, err = parseChecksums(data, "missing.zip")
+	if !errors.Is(err, ErrChecksumNotFound) {
+		t.Fatalf("expected ErrChecksumNotFound, got %v", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	file, err := download(server.URL, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	_, err = download(server.URL, "0000")
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	entries, _ := os.ReadDir(BinPath())
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".download-") {
+			t.Fatalf("left behind %s", entry.Name())
+		}
+	}
+}
@@ -2,12 +2,10 @@ package global
 
 import (
 	"archive/zip"
-	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -47,6 +45,9 @@ func ConfigDir() string {
 func NeedsPulumi() bool {
 	path := PulumiPath()
 	slog.Info("checking for pulumi", "path", path)
+	if Offline() && missingPlugins() {
+		return true
+	}
 	if _, err := os.Stat(path); err != nil {
 		return true
 	}
@@ -62,6 +63,9 @@ func NeedsPulumi() bool {
 
 func InstallPulumi() error {
 	slog.Info("pulumi install")
+	if Offline() {
+		return installPulumiOffline()
+	}
 	var osArch string
 
 	switch runtime.GOOS {
@@ -89,27 +93,29 @@ func InstallPulumi() error {
 		fileExtension = ".zip"
 	}
 
-	url := fmt.Sprintf("https://github.com/pulumi/pulumi/releases/download/%v/pulumi-%s-%s%s", PULUMI_VERSION, PULUMI_VERSION, osArch, fileExtension)
-	slog.Info("pulumi downloading", "url", url)
-
-	resp, err := http.Get(url)
+	filename := fmt.Sprintf("pulumi-%s-%s%s", PULUMI_VERSION, osArch, fileExtension)
+	base := "https://github.com/pulumi/pulumi/releases/download/" + PULUMI_VERSION + "/"
+	checksum, err := fetchChecksum(base+"pulumi-"+strings.TrimPrefix(PULUMI_VERSION, "v")+"-checksums.txt", filename)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download pulumi: HTTP status %d", resp.StatusCode)
+	file, err := download(base+filename, checksum)
+	if err != nil {
+		return err
 	}
+	defer os.Remove(file.Name())
+	defer file.Close()
 
 	tmp := filepath.Join(BinPath(), ".tmp")
+	os.RemoveAll(tmp)
 	err = os.MkdirAll(tmp, 0755)
 	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(tmp)
 	switch fileExtension {
 	case ".tar.gz":
-		gzr, err := gzip.NewReader(resp.Body)
+		gzr, err := gzip.NewReader(file)
 		if err != nil {
 			return err
 		}
@@ -127,13 +133,21 @@ func InstallPulumi() error {
 	if err != nil {
 		return err
 	}
+	// the pulumi binary is moved last so an interrupted install is retried
+	// by NeedsPulumi
 	for _, file := range entries {
+		if file.Name() == "pulumi" {
+			continue
+		}
 		err = os.Rename(filepath.Join(tmp, file.Name()), filepath.Join(BinPath(), file.Name()))
 		if err != nil {
 			return err
 		}
 	}
-	os.RemoveAll(tmp)
+	err = os.Rename(filepath.Join(tmp, "pulumi"), PulumiPath())
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -174,6 +188,9 @@ func NeedsBun() bool {
 
 func InstallBun() error {
 	slog.Info("bun install")
+	if Offline() {
+		return installBunOffline()
+	}
 	goos := runtime.GOOS
 	arch := runtime.GOARCH
 	bunPath := BunPath()
@@ -194,22 +211,22 @@ func InstallBun() error {
 		return fmt.Errorf("unsupported platform: %s %s", goos, arch)
 	}
 
-	url := "https://github.com/oven-sh/bun/releases//download/bun-v" + BUN_VERSION + "/" + filename
-	slog.Info("bun downloading", "url", url)
-	response, err := http.Get(url)
+	base := "https://github.com/oven-sh/bun/releases/download/bun-v" + BUN_VERSION + "/"
+	checksum, err := fetchChecksum(base+"SHASUMS256.txt", filename)
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
-	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", response.Status)
+	archive, err := download(base+filename, checksum)
+	if err != nil {
+		return err
 	}
-	bodyBytes, err := io.ReadAll(response.Body)
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	info, err := archive.Stat()
 	if err != nil {
 		return err
 	}
-	readerAt := bytes.NewReader(bodyBytes)
-	zipReader, err := zip.NewReader(readerAt, readerAt.Size())
+	zipReader, err := zip.NewReader(archive, info.Size())
 	if err != nil {
 		return err
 	}
@@ -229,18 +246,15 @@ func InstallBun() error {
 			defer outFile.Close()
 
 			_, err = io.Copy(outFile, f)
-			if err != nil {
-				return err
-			}
 			outFile.Close()
-
-			err = os.Rename(tmpFile, bunPath)
-			if err != nil {
-				return err
+			if err == nil {
+				err = os.Chmod(tmpFile, 0755)
+			}
+			if err == nil {
+				err = os.Rename(tmpFile, bunPath)
 			}
-
-			err = os.Chmod(bunPath, 0755)
 			if err != nil {
+				os.Remove(tmpFile)
 				return err
 			}
 		}
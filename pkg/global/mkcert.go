@@ -2,9 +2,7 @@ package global
 
 import (
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -12,6 +10,13 @@ import (
 
 const MKCERT_VERSION = "1.4.4"
 
+// mkcertChecksums are the sha256 of the release assets of MKCERT_VERSION by
+// os and arch. mkcert doesn't publish a checksums file, so they're pinned here
+// and have to be updated along with the version.
+var mkcertChecksums = map[string]string{
+	"linux-amd64": "6d31c65b03972c6dc4a14ab429f2928300518b26503f58723e532d1b0a3bbb52",
+}
+
 func EnsureMkcert() error {
 	binPath := filepath.Join(BinPath(), "mkcert")
 	if _, err := os.Stat(binPath); err == nil {
@@ -43,26 +48,22 @@ func EnsureMkcert() error {
 		fileExtension = ".exe"
 	}
 
-	url := fmt.Sprintf("https://github.com/FiloSottile/mkcert/releases/download/v%v/mkcert-v%v-%s%s", MKCERT_VERSION, MKCERT_VERSION, osArch, fileExtension)
-	slog.Info("mkcert downloading", "url", url)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download mkcert: HTTP status %d", resp.StatusCode)
+	filename := fmt.Sprintf("mkcert-v%v-%s%s", MKCERT_VERSION, osArch, fileExtension)
+	checksum, ok := mkcertChecksums[osArch]
+	if !ok {
+		return fmt.Errorf("%w for %s", ErrChecksumNotFound, filename)
 	}
-	// write to binPath
-	file, err := os.Create(binPath)
+	file, err := download(fmt.Sprintf("https://github.com/FiloSottile/mkcert/releases/download/v%v/%s", MKCERT_VERSION, filename), checksum)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	io.Copy(file, resp.Body)
-	err = os.Chmod(binPath, 0755)
+	file.Close()
+	err = os.Chmod(file.Name(), 0755)
+	if err == nil {
+		err = os.Rename(file.Name(), binPath)
+	}
 	if err != nil {
+		os.Remove(file.Name())
 		return err
 	}
 	return nil
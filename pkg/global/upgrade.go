@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -66,41 +65,48 @@ func Upgrade(existingVersion string, nextVersion string) (string, error) {
 	if nextVersion == existingVersion {
 		return nextVersion, nil
 	}
-	url := "https://github.com/sst/sst/releases/download/" + nextVersion + "/sst-" + filename
-	slog.Info("downloading", "url", url)
-	resp, err := http.Get(url)
+	base := "https://github.com/sst/sst/releases/download/" + nextVersion + "/"
+	checksum, err := fetchChecksum(base+"checksums.txt", "sst-"+filename)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected HTTP status when downloading release: %s", resp.Status)
+	file, err := download(base+"sst-"+filename, checksum)
+	if err != nil {
+		return "", err
 	}
+	defer os.Remove(file.Name())
+	defer file.Close()
 
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
 
+	// extract next to the install and swap it in once everything is there
 	sstBinPath := filepath.Join(homeDir, ".sst", "bin")
-	os.RemoveAll(sstBinPath)
-	if err := os.MkdirAll(sstBinPath, os.ModePerm); err != nil {
+	tmp := sstBinPath + ".tmp"
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
 		return "", err
 	}
+	defer os.RemoveAll(tmp)
 
-	// Assuming we have a variable `resp` which is the response from a *http.Request
-	body, err := gzip.NewReader(resp.Body)
+	body, err := gzip.NewReader(file)
 	if err != nil {
 		return "", err
 	}
 	defer body.Close()
 
-	if err := untar(body, sstBinPath); err != nil {
+	if err := untar(body, tmp); err != nil {
 		return "", err
 	}
 
-	if err := os.Chmod(filepath.Join(sstBinPath, "sst"), 0755); err != nil {
+	if err := os.Chmod(filepath.Join(tmp, "sst"), 0755); err != nil {
+		return "", err
+	}
+
+	os.RemoveAll(sstBinPath)
+	if err := os.Rename(tmp, sstBinPath); err != nil {
 		return "", err
 	}
 
@@ -3,6 +3,7 @@ package id
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"time"
 )
 
@@ -39,3 +40,20 @@ func generateID(descending bool) string {
 
 	return string(result)
 }
+
+// DescendingTime returns the time a descending ID was generated at.
+func DescendingTime(id string) (time.Time, error) {
+	if len(id) != LENGTH {
+		return time.Time{}, fmt.Errorf("invalid id %q", id)
+	}
+	timeBytes := make([]byte, 8)
+	_, err := hex.Decode(timeBytes, []byte(id[:16]))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var now int64
+	for i := 0; i < 8; i++ {
+		now |= int64(timeBytes[i]) << (56 - 8*i)
+	}
+	return time.UnixMilli(^now), nil
+}
@@ -37,3 +37,16 @@ func TestDescending(t *testing.T) {
 	run(t, id.Descending, func(a, b string) bool { return a >= b }, "descending")
 }
 
+func TestDescendingTime(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+	result, err := id.DescendingTime(id.Descending())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Before(before) || result.After(time.Now()) {
+		t.Errorf("Expected time around %v, got %v", before, result)
+	}
+	if _, err := id.DescendingTime("invalid"); err == nil {
+		t.Errorf("Expected error for invalid id")
+	}
+}
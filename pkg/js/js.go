@@ -12,16 +12,22 @@ import (
 )
 
 type EvalOptions struct {
-	Dir     string
-	Outfile string
-	Code    string
-	Env     []string
-	Globals string
-	Banner  string
-	Inject  []string
-	Define  map[string]string
+	Dir string
+	// WorkingDir is the .sst directory, it defaults to the one in Dir
+	WorkingDir string
+	Outfile    string
+	Code       string
+	Env        []string
+	Globals    string
+	Banner     string
+	Inject     []string
+	Define     map[string]string
 }
 
+// StdinSourcefile is how EvalOptions.Code shows up in the inputs of the
+// metafile. It's not a file on disk.
+const StdinSourcefile = "eval.ts"
+
 type PackageJson struct {
 	Version         string                 `json:"version"`
 	Dependencies    map[string]string      `json:"dependencies"`
@@ -48,9 +54,13 @@ type Metafile struct {
 }
 
 func Build(input EvalOptions) (esbuild.BuildResult, error) {
+	workingDir := input.WorkingDir
+	if workingDir == "" {
+		workingDir = filepath.Join(input.Dir, ".sst")
+	}
 	outfile := input.Outfile
 	if outfile == "" {
-		outfile = filepath.Join(input.Dir, ".sst", "platform", fmt.Sprintf("sst.config.%v.mjs", time.Now().UnixMilli()))
+		outfile = filepath.Join(workingDir, "platform", fmt.Sprintf("sst.config.%v.mjs", time.Now().UnixMilli()))
 	}
 	slog.Info("esbuild building", "out", outfile)
 	result := esbuild.Build(esbuild.BuildOptions{
@@ -70,11 +80,11 @@ const __dirname = topLevelFileUrlToPath(new topLevelURL(".", import.meta.url))
 		Stdin: &esbuild.StdinOptions{
 			Contents:   input.Code,
 			ResolveDir: input.Dir,
-			Sourcefile: "eval.ts",
+			Sourcefile: StdinSourcefile,
 			Loader:     esbuild.LoaderTS,
 		},
 		NodePaths: []string{
-			filepath.Join(input.Dir, ".sst", "platform", "node_modules"),
+			filepath.Join(workingDir, "platform", "node_modules"),
 		},
 		Plugins: []esbuild.Plugin{
 			{
@@ -127,7 +137,7 @@ const __dirname = topLevelFileUrlToPath(new topLevelURL(".", import.meta.url))
 	analysis := esbuild.AnalyzeMetafile(result.Metafile, esbuild.AnalyzeMetafileOptions{
 		Verbose: true,
 	})
-	os.WriteFile(filepath.Join(input.Dir, ".sst", "esbuild.json"), []byte(analysis), 0644)
+	os.WriteFile(filepath.Join(workingDir, "esbuild.json"), []byte(analysis), 0644)
 
 	return result, nil
 }
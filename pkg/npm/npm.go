@@ -15,6 +15,10 @@ type Package struct {
 		Name    string `json:"name"`
 		Version string `json:"version"`
 	}
+	Dist struct {
+		Tarball   string `json:"tarball"`
+		Integrity string `json:"integrity"`
+	} `json:"dist"`
 }
 
 func Get(name string, version string) (*Package, error) {
@@ -0,0 +1,172 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/pkg/global"
+)
+
+var ErrBundleVersion = fmt.Errorf("offline bundle was made with a different version of sst")
+
+type pulumiPlugin struct {
+	Name    string
+	Version string
+	Server  string
+}
+
+// Bundle writes everything the installed providers need to a tarball that
+// can be used with SST_OFFLINE on a machine without internet access
+func (p *Project) Bundle(out string) error {
+	plugins, err := p.pulumiPlugins()
+	if err != nil {
+		return err
+	}
+	installed := []string{}
+	for _, plugin := range plugins {
+		dir, err := global.InstallPlugin(plugin.Name, plugin.Version, plugin.Server)
+		if err != nil {
+			return err
+		}
+		installed = append(installed, dir)
+	}
+	writer, err := global.NewBundleWriter(out)
+	if err != nil {
+		return err
+	}
+	err = writer.AddTools(p.version, installed)
+	if err != nil {
+		writer.Close()
+		os.Remove(out)
+		return err
+	}
+	err = writer.AddFile(p.PathProviderLock(), "platform/provider-lock.json")
+	if err != nil {
+		writer.Close()
+		os.Remove(out)
+		return err
+	}
+	err = writer.AddDir(filepath.Join(p.PathPlatformDir(), "node_modules"), "platform/node_modules")
+	if err != nil {
+		writer.Close()
+		os.Remove(out)
+		return err
+	}
+	return writer.Close()
+}
+
+// pulumiPlugins returns the resource plugins the installed packages need,
+// from the pulumi field in their package.json
+func (p *Project) pulumiPlugins() ([]pulumiPlugin, error) {
+	root := filepath.Join(p.PathPlatformDir(), "node_modules")
+	paths, err := filepath.Glob(filepath.Join(root, "*", "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	scoped, err := filepath.Glob(filepath.Join(root, "@*", "*", "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	result := []pulumiPlugin{}
+	for _, path := range append(paths, scoped...) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var pkg struct {
+			Version string `json:"version"`
+			Pulumi  *struct {
+				Resource bool   `json:"resource"`
+				Name     string `json:"name"`
+				Version  string `json:"version"`
+				Server   string `json:"server"`
+			} `json:"pulumi"`
+		}
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			slog.Info("could not parse package.json", "path", path, "err", err)
+			continue
+		}
+		if pkg.Pulumi == nil || !pkg.Pulumi.Resource || pkg.Pulumi.Name == "" {
+			continue
+		}
+		version := pkg.Pulumi.Version
+		if version == "" {
+			version = pkg.Version
+		}
+		key := pkg.Pulumi.Name + "@" + version
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, pulumiPlugin{
+			Name:    pkg.Pulumi.Name,
+			Version: version,
+			Server:  pkg.Pulumi.Server,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name+result[i].Version < result[j].Name+result[j].Version
+	})
+	return result, nil
+}
+
+func readOfflineBundle(version string) (string, error) {
+	bundle, err := global.ReadBundle()
+	if err != nil {
+		return "", err
+	}
+	if bundle.Version != version {
+		return "", fmt.Errorf("%w: %s", ErrBundleVersion, bundle.Version)
+	}
+	return global.OfflineDir()
+}
+
+// findOfflineProvider looks up a provider in the lock of the offline bundle
+// instead of the npm registry
+func (p *Project) findOfflineProvider(name string, version string) (*ProviderLockEntry, error) {
+	dir, err := readOfflineBundle(p.version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "platform", "provider-lock.json"))
+	if err != nil {
+		return nil, err
+	}
+	var lock ProviderLock
+	err = json.Unmarshal(data, &lock)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range lock {
+		if entry.Name != name {
+			continue
+		}
+		if version != "latest" && strings.TrimPrefix(version, "v") != entry.Version {
+			return nil, fmt.Errorf("provider %s is %s in the offline bundle instead of %s", name, entry.Version, version)
+		}
+		return entry, nil
+	}
+	return nil, fmt.Errorf("provider %s not found in the offline bundle", name)
+}
+
+// fetchOfflineDeps copies the installed packages from the offline bundle
+// instead of running a package manager
+func (p *Project) fetchOfflineDeps() error {
+	slog.Info("fetching deps offline")
+	dir, err := readOfflineBundle(p.version)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(p.PathPlatformDir(), "node_modules")
+	err = os.RemoveAll(target)
+	if err != nil {
+		return err
+	}
+	return global.CopyDir(filepath.Join(dir, "platform", "node_modules"), target)
+}
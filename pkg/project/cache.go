@@ -0,0 +1,177 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/pkg/flag"
+	"github.com/sst/ion/pkg/js"
+)
+
+// appCache is the output of evaluating the app function in the config. It's
+// reused as long as the files the config imports, and the environment
+// variables they read, haven't changed.
+type appCache struct {
+	Key    string   `json:"key"`
+	Files  []string `json:"files"`
+	Env    []string `json:"env"`
+	Output []byte   `json:"output"`
+}
+
+var envPattern = regexp.MustCompile(`process\.env\.([A-Za-z_][A-Za-z0-9_]*)|process\.env\[\s*["']([^"']+)["']\s*\]`)
+
+func (p Project) pathAppCache(stage string) string {
+	return filepath.Join(p.PathWorkingDir(), "cache", "app."+stage+".json")
+}
+
+func readAppCache(path string, version string, stage string) ([]byte, bool) {
+	if flag.SST_NO_CONFIG_CACHE {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var cache appCache
+	err = json.Unmarshal(data, &cache)
+	if err != nil {
+		return nil, false
+	}
+	key, err := appCacheKey(version, stage, cache.Files, cache.Env)
+	if err != nil || key != cache.Key {
+		return nil, false
+	}
+	return cache.Output, true
+}
+
+func writeAppCache(path string, version string, stage string, metafile string, output []byte) error {
+	files, err := metafileInputs(metafile)
+	if err != nil {
+		return err
+	}
+	env, err := appCacheEnv(files)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	key, err := appCacheKey(version, stage, files, env)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(appCache{
+		Key:    key,
+		Files:  files,
+		Env:    env,
+		Output: output,
+	})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// appCacheEnv returns the environment variables the config reads. The files
+// in node_modules are skipped since they're only reread when they change. If
+// the environment is read in a way that can't be followed, the config can't
+// be cached.
+func appCacheEnv(files []string) ([]string, error) {
+	names := map[string]bool{}
+	for _, file := range files {
+		if strings.Contains(file, string(filepath.Separator)+"node_modules"+string(filepath.Separator)) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		source := string(data)
+		matches := envPattern.FindAllStringSubmatch(source, -1)
+		if len(matches) != strings.Count(source, "process.env") {
+			return nil, fmt.Errorf("%s reads the environment dynamically", file)
+		}
+		for _, match := range matches {
+			names[match[1]+match[2]] = true
+		}
+	}
+	result := []string{}
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func appCacheKey(version string, stage string, files []string, env []string) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "version:%s\nstage:%s\n", version, stage)
+	err := hashInputs(hash, files, func(file string) string { return file })
+	if err != nil {
+		return "", err
+	}
+	for _, name := range env {
+		value, ok := os.LookupEnv(name)
+		fmt.Fprintf(hash, "env:%s=%v:%s\n", name, ok, value)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// metafileInputs returns the files esbuild read as absolute paths, sorted.
+// The metafile lists them relative to the directory esbuild ran in.
+func metafileInputs(metafile string) ([]string, error) {
+	var meta js.Metafile
+	err := json.Unmarshal([]byte(metafile), &meta)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for key := range meta.Inputs {
+		if key == js.StdinSourcefile {
+			continue
+		}
+		abs, err := filepath.Abs(key)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, abs)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// hashInputs writes the name and contents of every input to the hash, in the
+// order of their names. An input that can't be read is an error, so a deleted
+// file doesn't go unnoticed.
+func hashInputs(hash io.Writer, files []string, name func(file string) string) error {
+	sorted := append([]string{}, files...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return name(sorted[i]) < name(sorted[j])
+	})
+	for _, file := range sorted {
+		fmt.Fprintf(hash, "file:%s\n", name(file))
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppCache(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "sst.config.ts")
+	os.WriteFile(config, []byte(`export default { app() { return { name: process.env.APP_NAME } } }`), 0644)
+	metafile, _ := json.Marshal(map[string]interface{}{
+		"inputs": map[string]interface{}{config: map[string]interface{}{}},
+	})
+	path := filepath.Join(dir, ".sst", "cache", "app.dev.json")
+	t.Setenv("APP_NAME", "one")
+
+	err := writeAppCache(path, "1.0.0", "dev", string(metafile), []byte("output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, ok := readAppCache(path, "1.0.0", "dev")
+	if !ok || string(output) != "output" {
+		t.Fatalf("expected cache hit, got %q %v", output, ok)
+	}
+	if _, ok := readAppCache(path, "1.0.0", "production"); ok {
+		t.Fatal("expected a different stage to miss")
+	}
+	if _, ok := readAppCache(path, "1.0.1", "dev"); ok {
+		t.Fatal("expected a different version to miss")
+	}
+	t.Setenv("APP_NAME", "two")
+	if _, ok := readAppCache(path, "1.0.0", "dev"); ok {
+		t.Fatal("expected a changed env var to miss")
+	}
+	t.Setenv("APP_NAME", "one")
+	os.WriteFile(config, []byte(`export default { app() { return { name: "three" } } }`), 0644)
+	if _, ok := readAppCache(path, "1.0.0", "dev"); ok {
+		t.Fatal("expected a changed file to miss")
+	}
+
+	os.WriteFile(config, []byte(`export default { app() { return { name: process.env[key] } } }`), 0644)
+	err = writeAppCache(path, "1.0.0", "dev", string(metafile), []byte("output"))
+	if err == nil {
+		t.Fatal("expected dynamic env access to skip the cache")
+	}
+	if _, ok := readAppCache(path, "1.0.0", "dev"); ok {
+		t.Fatal("expected the stale cache to be removed")
+	}
+}
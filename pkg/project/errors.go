@@ -0,0 +1,102 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sst/ion/platform"
+)
+
+// CommonError is an entry in platform/common-errors.json. It matches errors
+// that contain the message or, if set, match the pattern.
+type CommonError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Pattern string `json:"pattern,omitempty"`
+	// Providers limits the error to resources from these providers
+	Providers []string `json:"providers,omitempty"`
+	Link      string   `json:"link,omitempty"`
+	// Transient errors go away on their own and can be retried
+	Transient bool     `json:"transient,omitempty"`
+	Short     []string `json:"short"`
+	Long      []string `json:"long"`
+
+	regex *regexp.Regexp
+}
+
+var commonErrors = sync.OnceValues(func() ([]CommonError, error) {
+	return parseCommonErrors(platform.CommonErrors)
+})
+
+// CommonErrors returns the registry of known errors shipped with the platform
+func CommonErrors() ([]CommonError, error) {
+	return commonErrors()
+}
+
+func parseCommonErrors(data []byte) ([]CommonError, error) {
+	var result []CommonError
+	err := json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		if result[i].Pattern == "" {
+			continue
+		}
+		result[i].regex, err = regexp.Compile(result[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for common error %s: %w", result[i].Code, err)
+		}
+	}
+	return result, nil
+}
+
+func (e *CommonError) Match(message string, urn string) bool {
+	if len(e.Providers) > 0 && urn != "" && !slices.Contains(e.Providers, urnProvider(urn)) {
+		return false
+	}
+	if e.regex != nil {
+		return e.regex.MatchString(message)
+	}
+	return strings.Contains(message, e.Message)
+}
+
+// Help is the text shown under the error in the CLI
+func (e *CommonError) Help() []string {
+	help := append([]string{}, e.Short...)
+	if e.Link != "" {
+		help = append(help, "Learn more about this "+e.Link)
+	}
+	return help
+}
+
+// commonErrorHelp returns the help for every common error the message matches
+func commonErrorHelp(message string, urn string) []string {
+	help := []string{}
+	errors, err := CommonErrors()
+	if err != nil {
+		return help
+	}
+	for _, commonError := range errors {
+		if commonError.Match(message, urn) {
+			help = append(help, commonError.Help()...)
+		}
+	}
+	return help
+}
+
+// urnProvider returns the package of the resource type in a URN, like "aws"
+// for urn:pulumi:dev::app::sst:aws:Function$aws:lambda/function:Function::Fn
+func urnProvider(urn string) string {
+	parts := strings.Split(urn, "::")
+	if len(parts) < 4 {
+		return ""
+	}
+	types := strings.Split(parts[2], "$")
+	provider, _, _ := strings.Cut(types[len(types)-1], ":")
+	return provider
+}
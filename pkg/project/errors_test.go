@@ -0,0 +1,39 @@
+package project
+
+import (
+	"testing"
+)
+
+func TestCommonErrors(t *testing.T) {
+	errors, err := CommonErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := map[string]bool{}
+	for _, item := range errors {
+		if item.Code == "" || item.Message == "" || len(item.Short) == 0 {
+			t.Fatalf("common error %q is missing a field", item.Code)
+		}
+		if codes[item.Code] {
+			t.Fatalf("duplicate common error %q", item.Code)
+		}
+		codes[item.Code] = true
+		// the message is what's shown in the docs so it should match itself
+		if item.regex == nil && !item.Match(item.Message, "") {
+			t.Fatalf("common error %q does not match its own message", item.Code)
+		}
+	}
+
+	lambda := "urn:pulumi:dev::app::sst:aws:Function$aws:lambda/function:Function::Api"
+	worker := "urn:pulumi:dev::app::sst:cloudflare:Worker$cloudflare:index/workerScript:WorkerScript::Api"
+	message := "creating Lambda Function (app-dev-Api): operation error Lambda: CreateFunction, InvalidParameterValueException: The role defined for the function cannot be assumed by Lambda."
+	if help := commonErrorHelp(message, lambda); len(help) == 0 {
+		t.Fatal("expected help for the lambda role error")
+	}
+	if help := commonErrorHelp(message, worker); len(help) != 0 {
+		t.Fatal("expected aws errors to not match cloudflare resources")
+	}
+	if help := commonErrorHelp("something else went wrong", lambda); len(help) != 0 {
+		t.Fatal("expected no help for an unknown error")
+	}
+}
@@ -0,0 +1,220 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// Graph is the resources of a stage with how they relate to each other
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	URN    string `json:"urn"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Parent string `json:"parent,omitempty"`
+}
+
+type GraphEdgeKind string
+
+const (
+	// GraphEdgeParent goes from a component to its child
+	GraphEdgeParent GraphEdgeKind = "parent"
+	// GraphEdgeDependency goes from a resource to a resource it depends on
+	GraphEdgeDependency GraphEdgeKind = "dependency"
+	// GraphEdgeLink goes from a resource to a component it's linked to. Only
+	// functions record their links in the state, so links of other components
+	// are missing.
+	GraphEdgeLink GraphEdgeKind = "link"
+)
+
+type GraphEdge struct {
+	From string        `json:"from"`
+	To   string        `json:"to"`
+	Kind GraphEdgeKind `json:"kind"`
+}
+
+var ErrComponentNotFound = fmt.Errorf("component not found")
+
+// hidden resources are internal to pulumi or sst and only add noise. LinkRef
+// only records the target of a link, not what links to it.
+func graphHidden(kind string) bool {
+	return kind == "pulumi:pulumi:Stack" ||
+		kind == "sst:sst:LinkRef" ||
+		kind == "sst:sst:Version" ||
+		strings.HasPrefix(kind, "pulumi:providers:")
+}
+
+// BuildGraph builds the graph of the resources in the state. If a component
+// is passed in, only the resources in it and the ones they're directly
+// connected to are included.
+func BuildGraph(resources []apitype.ResourceV3, component string) (*Graph, error) {
+	nodes := map[string]GraphNode{}
+	edges := []GraphEdge{}
+	components := map[string]string{}
+	for _, item := range resources {
+		if graphHidden(string(item.Type)) {
+			continue
+		}
+		urn := string(item.URN)
+		parent := string(item.Parent)
+		if parent != "" && graphHidden(string(item.Parent.Type())) {
+			parent = ""
+		}
+		nodes[urn] = GraphNode{
+			URN:    urn,
+			Name:   item.URN.Name(),
+			Type:   string(item.Type),
+			Parent: parent,
+		}
+		if !item.Custom && strings.HasPrefix(string(item.Type), "sst:") {
+			components[item.URN.Name()] = urn
+		}
+	}
+
+	for _, item := range resources {
+		urn := string(item.URN)
+		if _, ok := nodes[urn]; !ok {
+			continue
+		}
+		if parent := nodes[urn].Parent; parent != "" {
+			edges = append(edges, GraphEdge{From: parent, To: urn, Kind: GraphEdgeParent})
+		}
+		for _, dependency := range item.Dependencies {
+			if _, ok := nodes[string(dependency)]; ok {
+				edges = append(edges, GraphEdge{From: urn, To: string(dependency), Kind: GraphEdgeDependency})
+			}
+		}
+		for _, link := range graphLinks(item) {
+			if target, ok := components[link]; ok {
+				edges = append(edges, GraphEdge{From: urn, To: target, Kind: GraphEdgeLink})
+			}
+		}
+	}
+
+	if component != "" {
+		root, ok := components[component]
+		if !ok {
+			return nil, ErrComponentNotFound
+		}
+		included := map[string]bool{}
+		for urn := range nodes {
+			for current := urn; current != ""; current = nodes[current].Parent {
+				if current == root {
+					included[urn] = true
+					break
+				}
+			}
+		}
+		filtered := []GraphEdge{}
+		neighbors := map[string]bool{}
+		for _, edge := range edges {
+			if !included[edge.From] && !included[edge.To] {
+				continue
+			}
+			filtered = append(filtered, edge)
+			neighbors[edge.From] = true
+			neighbors[edge.To] = true
+		}
+		for urn := range nodes {
+			if !included[urn] && !neighbors[urn] {
+				delete(nodes, urn)
+			}
+		}
+		edges = filtered
+	}
+
+	graph := &Graph{Nodes: []GraphNode{}, Edges: edges}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].URN < graph.Nodes[j].URN
+	})
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph, nil
+}
+
+// graphLinks returns the names of the components a resource is linked to
+func graphLinks(item apitype.ResourceV3) []string {
+	outputs, ok := decrypt(item.Outputs).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	metadata, ok := outputs["_metadata"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	links, ok := metadata["links"].([]interface{})
+	if !ok {
+		return nil
+	}
+	result := []string{}
+	for _, link := range links {
+		if name, ok := link.(string); ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// DOT renders the graph for graphviz
+func (g *Graph) DOT() string {
+	ids := g.ids()
+	lines := []string{"digraph {", "  rankdir=LR;", "  node [shape=box];"}
+	for _, node := range g.Nodes {
+		lines = append(lines, fmt.Sprintf("  %s [label=%q];", ids[node.URN], node.Name+"\n"+node.Type))
+	}
+	for _, edge := range g.Edges {
+		style := ""
+		switch edge.Kind {
+		case GraphEdgeParent:
+			style = " [style=dashed, arrowhead=none]"
+		case GraphEdgeLink:
+			style = " [style=dotted, label=\"link\"]"
+		}
+		lines = append(lines, fmt.Sprintf("  %s -> %s%s;", ids[edge.From], ids[edge.To], style))
+	}
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n")
+}
+
+// Mermaid renders the graph as a mermaid flowchart
+func (g *Graph) Mermaid() string {
+	ids := g.ids()
+	lines := []string{"graph LR"}
+	for _, node := range g.Nodes {
+		label := strings.ReplaceAll(node.Name+"<br/>"+node.Type, `"`, "#quot;")
+		lines = append(lines, fmt.Sprintf("  %s[\"%s\"]", ids[node.URN], label))
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		switch edge.Kind {
+		case GraphEdgeParent:
+			arrow = "---"
+		case GraphEdgeLink:
+			arrow = "-. link .->"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", ids[edge.From], arrow, ids[edge.To]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (g *Graph) ids() map[string]string {
+	ids := map[string]string{}
+	for i, node := range g.Nodes {
+		ids[node.URN] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
@@ -0,0 +1,72 @@
+package project
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestBuildGraph(t *testing.T) {
+	stack := resource.URN("urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev")
+	bucket := resource.URN("urn:pulumi:dev::app::sst:aws:Bucket::Uploads")
+	bucketV2 := resource.URN("urn:pulumi:dev::app::sst:aws:Bucket$aws:s3/bucketV2:BucketV2::UploadsBucket")
+	api := resource.URN("urn:pulumi:dev::app::sst:aws:Function::Api")
+	role := resource.URN("urn:pulumi:dev::app::sst:aws:Function$aws:iam/role:Role::ApiRole")
+	function := resource.URN("urn:pulumi:dev::app::sst:aws:Function$aws:lambda/function:Function::ApiFunction")
+	other := resource.URN("urn:pulumi:dev::app::sst:aws:Function::Cron")
+	resources := []apitype.ResourceV3{
+		{URN: stack, Type: "pulumi:pulumi:Stack"},
+		{URN: bucket, Type: "sst:aws:Bucket", Parent: stack},
+		{URN: bucketV2, Type: "aws:s3/bucketV2:BucketV2", Custom: true, Parent: bucket},
+		{URN: "urn:pulumi:dev::app::sst:sst:LinkRef::UploadsLinkRef", Type: "sst:sst:LinkRef", Parent: stack},
+		{URN: api, Type: "sst:aws:Function", Parent: stack, Outputs: map[string]interface{}{
+			"_metadata": map[string]interface{}{"links": []interface{}{"Uploads"}},
+		}},
+		{URN: role, Type: "aws:iam/role:Role", Custom: true, Parent: api, Dependencies: []resource.URN{bucketV2}},
+		{URN: function, Type: "aws:lambda/function:Function", Custom: true, Parent: api, Dependencies: []resource.URN{role}},
+		{URN: other, Type: "sst:aws:Function", Parent: stack},
+	}
+
+	graph, err := BuildGraph(resources, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Nodes) != 6 {
+		t.Fatalf("expected 6 nodes, got %d", len(graph.Nodes))
+	}
+	expected := []GraphEdge{
+		{From: string(api), To: string(bucket), Kind: GraphEdgeLink},
+		{From: string(role), To: string(bucketV2), Kind: GraphEdgeDependency},
+		{From: string(function), To: string(role), Kind: GraphEdgeDependency},
+		{From: string(bucket), To: string(bucketV2), Kind: GraphEdgeParent},
+	}
+	for _, edge := range expected {
+		found := false
+		for _, item := range graph.Edges {
+			found = found || item == edge
+		}
+		if !found {
+			t.Fatalf("missing edge %v", edge)
+		}
+	}
+
+	graph, err = BuildGraph(resources, "Uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range graph.Nodes {
+		if node.URN == string(other) || node.URN == string(function) {
+			t.Fatalf("expected %s to be filtered out", node.URN)
+		}
+	}
+	if !strings.Contains(graph.Mermaid(), "-. link .->") {
+		t.Fatal("expected the link edge in the mermaid output")
+	}
+
+	_, err = BuildGraph(resources, "Missing")
+	if err != ErrComponentNotFound {
+		t.Fatalf("expected ErrComponentNotFound, got %v", err)
+	}
+}
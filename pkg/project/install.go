@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/sst/ion/pkg/flag"
@@ -18,32 +19,43 @@ import (
 )
 
 func (p *Project) NeedsInstall() bool {
-	if len(p.app.Providers) != len(p.lock) {
+	if mismatches := p.lockMismatches(); len(mismatches) > 0 {
+		slog.Info("provider lock does not match", "mismatches", mismatches)
 		return true
 	}
 	for _, entry := range p.lock {
-		config := p.app.Providers[entry.Name].(map[string]interface{})
-		version := config["version"]
-		if version == nil || version == "" {
-			continue
-		}
-		slog.Info("checking provider", "name", entry.Name, "version", version, "compare", entry.Version)
-		if version != entry.Version {
+		installed := p.installedVersion(entry.Package)
+		slog.Info("checking provider", "name", entry.Name, "version", entry.Version, "installed", installed)
+		if installed != entry.Version {
 			return true
 		}
 	}
 	return false
 }
 
+// Install installs the providers in the config and updates the lock. With
+// SST_FROZEN set, the lock is installed as is and it's an error if it doesn't
+// match the config.
 func (p *Project) Install() error {
 	slog.Info("installing deps")
 
-	err := p.generateProviderLock()
-	if err != nil {
-		return err
+	if flag.SST_FROZEN {
+		err := p.checkLock()
+		if err != nil {
+			return err
+		}
+		err = p.verifyLock()
+		if err != nil {
+			return err
+		}
+	} else {
+		err := p.generateProviderLock()
+		if err != nil {
+			return err
+		}
 	}
 
-	err = p.writePackageJson()
+	err := p.writePackageJson()
 	if err != nil {
 		return err
 	}
@@ -58,6 +70,9 @@ func (p *Project) Install() error {
 		return err
 	}
 
+	if flag.SST_FROZEN {
+		return nil
+	}
 	err = p.writeProviderLock()
 	if err != nil {
 		return err
@@ -89,7 +104,12 @@ func (p *Project) writePackageJson() error {
 	dependencies := result["dependencies"].(map[string]interface{})
 	for _, entry := range p.lock {
 		slog.Info("adding dependency", "name", entry.Name)
-		dependencies[entry.Package] = entry.Version
+		version := entry.Version
+		if flag.SST_FROZEN && !global.Offline() {
+			// install the exact tarball verifyLock checked
+			version = "file:" + filepath.ToSlash(p.pathProviderTarball(entry))
+		}
+		dependencies[entry.Package] = version
 	}
 
 	dataToWrite, err := json.MarshalIndent(result, "", "  ")
@@ -154,6 +174,9 @@ func (p *Project) writeTypes() error {
 
 func (p *Project) fetchDeps() error {
 	slog.Info("fetching deps")
+	if global.Offline() {
+		return p.fetchOfflineDeps()
+	}
 	manager := global.BunPath()
 	if flag.NO_BUN {
 		manager = "npm"
@@ -168,17 +191,22 @@ func (p *Project) fetchDeps() error {
 }
 
 type ProviderLockEntry struct {
-	Name    string `json:"name"`
-	Package string `json:"package"`
-	Version string `json:"version"`
-	Alias   string `json:"alias"`
+	Name      string `json:"name"`
+	Package   string `json:"package"`
+	Version   string `json:"version"`
+	Alias     string `json:"alias"`
+	Resolved  string `json:"resolved,omitempty"`
+	Integrity string `json:"integrity,omitempty"`
 }
 
 type ProviderLock = []*ProviderLockEntry
 
 func (p *Project) loadProviderLock() error {
-	lockPath := filepath.Join(p.PathPlatformDir(), "provider-lock.json")
-	data, err := os.ReadFile(lockPath)
+	data, err := os.ReadFile(p.PathProviderLock())
+	if os.IsNotExist(err) {
+		// the lock used to only be kept in the platform directory
+		data, err = os.ReadFile(filepath.Join(p.PathPlatformDir(), "provider-lock.json"))
+	}
 	if err != nil {
 		p.lock = ProviderLock{}
 		return nil
@@ -190,21 +218,42 @@ func (p *Project) loadProviderLock() error {
 	return nil
 }
 
+// generateProviderLock resolves the providers in the config. Providers that
+// are already locked to a version the config allows are kept as they are.
 func (p *Project) generateProviderLock() error {
+	previous := map[string]*ProviderLockEntry{}
+	for _, entry := range p.lock {
+		previous[entry.Name] = entry
+	}
 	var wg errgroup.Group
 	out := ProviderLock{}
 	results := make(chan ProviderLockEntry)
-	for name, config := range p.app.Providers {
+	for name := range p.app.Providers {
 		n := name
-		version := config.(map[string]interface{})["version"]
-		if version == nil || version == "" {
+		version := p.providerVersion(n)
+		if entry, ok := previous[n]; ok && entry.Integrity != "" && (version == "" || version == entry.Version) {
+			wg.Go(func() error {
+				results <- *entry
+				return nil
+			})
+			continue
+		}
+		if version == "" {
 			version = "latest"
 		}
 		wg.Go(func() error {
-			result, err := FindProvider(n, version.(string))
+			find := FindProvider
+			if global.Offline() {
+				find = p.findOfflineProvider
+			}
+			result, err := find(n, version)
 			if err != nil {
 				return err
 			}
+			// the same version of a package should never change
+			if entry, ok := previous[n]; ok && entry.Version == result.Version && entry.Integrity != "" && entry.Integrity != result.Integrity {
+				return fmt.Errorf("%w: %s %s was %s in the lock but is now %s", ErrProviderIntegrity, result.Package, result.Version, entry.Integrity, result.Integrity)
+			}
 			results <- *result
 			return nil
 		})
@@ -244,22 +293,26 @@ func FindProvider(name string, version string) (*ProviderLockEntry, error) {
 		}
 		alias = strings.ReplaceAll(alias, "-", "")
 		return &ProviderLockEntry{
-			Name:    name,
-			Package: pkg.Name,
-			Version: pkg.Version,
-			Alias:   alias,
+			Name:      name,
+			Package:   pkg.Name,
+			Version:   pkg.Version,
+			Alias:     alias,
+			Resolved:  pkg.Dist.Tarball,
+			Integrity: pkg.Dist.Integrity,
 		}, nil
 	}
 	return nil, fmt.Errorf("provider %s not found", name)
 }
 
 func (p *Project) writeProviderLock() error {
-	lockPath := filepath.Join(p.PathPlatformDir(), "provider-lock.json")
+	sort.Slice(p.lock, func(i, j int) bool {
+		return p.lock[i].Name < p.lock[j].Name
+	})
 	data, err := json.MarshalIndent(p.lock, "", "  ")
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(lockPath, data, 0644)
+	err = os.WriteFile(p.PathProviderLock(), data, 0644)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,208 @@
+package project
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/pkg/global"
+	projectpath "github.com/sst/ion/pkg/project/path"
+	"golang.org/x/sync/errgroup"
+)
+
+var ErrLockMismatch = fmt.Errorf("provider lock does not match the config")
+var ErrProviderIntegrity = fmt.Errorf("provider integrity check failed")
+
+type LockMismatchError struct {
+	Mismatches []string
+}
+
+func (e *LockMismatchError) Error() string {
+	lines := []string{ErrLockMismatch.Error()}
+	for _, mismatch := range e.Mismatches {
+		lines = append(lines, "  "+mismatch)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *LockMismatchError) Is(target error) bool {
+	return target == ErrLockMismatch
+}
+
+// PathProviderLock is next to the config so it can be committed. A config
+// with a custom name gets its own lock, like sst.api.lock.json.
+func (p Project) PathProviderLock() string {
+	name := "sst.lock.json"
+	if app := projectpath.ResolveAppDir(p.PathConfig()); app != "" {
+		name = "sst." + app + ".lock.json"
+	}
+	return filepath.Join(p.PathRoot(), name)
+}
+
+// providerVersion is the version set for a provider in the config, or "" if
+// it isn't pinned
+func (p *Project) providerVersion(name string) string {
+	config, ok := p.app.Providers[name].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	version, _ := config["version"].(string)
+	return version
+}
+
+// lockMismatches lists the ways the config and the lock disagree
+func (p *Project) lockMismatches() []string {
+	result := []string{}
+	locked := map[string]*ProviderLockEntry{}
+	for _, entry := range p.lock {
+		locked[entry.Name] = entry
+		if _, ok := p.app.Providers[entry.Name]; !ok {
+			result = append(result, fmt.Sprintf("%s is in the lock but not in the config", entry.Name))
+		}
+	}
+	for name := range p.app.Providers {
+		entry, ok := locked[name]
+		if !ok {
+			result = append(result, fmt.Sprintf("%s is in the config but not in the lock", name))
+			continue
+		}
+		version := p.providerVersion(name)
+		if version != "" && version != entry.Version {
+			result = append(result, fmt.Sprintf("%s is %s in the config but %s in the lock", name, version, entry.Version))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkLock makes sure the lock can be installed as is, without resolving
+// anything
+func (p *Project) checkLock() error {
+	mismatches := p.lockMismatches()
+	for _, entry := range p.lock {
+		if entry.Integrity == "" {
+			mismatches = append(mismatches, fmt.Sprintf("%s has no integrity in the lock", entry.Name))
+		}
+	}
+	if len(mismatches) > 0 {
+		return &LockMismatchError{Mismatches: mismatches}
+	}
+	return nil
+}
+
+// verifyLock checks the package of every provider against the integrity in
+// the lock. The checked tarballs are kept so they can be installed as is,
+// otherwise the package manager would download them again unchecked.
+func (p *Project) verifyLock() error {
+	var wg errgroup.Group
+	for _, entry := range p.lock {
+		entry := entry
+		wg.Go(func() error {
+			if global.Offline() {
+				bundled, err := p.findOfflineProvider(entry.Name, entry.Version)
+				if err != nil {
+					return err
+				}
+				if bundled.Integrity != entry.Integrity {
+					return fmt.Errorf("%w: %s in the offline bundle is %s instead of %s", ErrProviderIntegrity, entry.Package, bundled.Integrity, entry.Integrity)
+				}
+				return nil
+			}
+			slog.Info("verifying provider", "package", entry.Package, "tarball", entry.Resolved)
+			resp, err := http.Get(entry.Resolved)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("failed to download %s: %s", entry.Resolved, resp.Status)
+			}
+			path := p.pathProviderTarball(entry)
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				return err
+			}
+			file, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+			if err != nil {
+				return err
+			}
+			err = checkIntegrity(io.TeeReader(resp.Body, file), entry.Integrity)
+			file.Close()
+			if err == nil {
+				err = os.Rename(file.Name(), path)
+			}
+			if err != nil {
+				os.Remove(file.Name())
+				if errors.Is(err, ErrProviderIntegrity) {
+					return fmt.Errorf("%w: %s %s", err, entry.Package, entry.Version)
+				}
+				return err
+			}
+			return nil
+		})
+	}
+	return wg.Wait()
+}
+
+// pathProviderTarball is where verifyLock keeps the tarball of a provider
+func (p *Project) pathProviderTarball(entry *ProviderLockEntry) string {
+	name := strings.ReplaceAll(strings.TrimPrefix(entry.Package, "@"), "/", "-")
+	return filepath.Join(p.PathPlatformDir(), "tarballs", name+"-"+entry.Version+".tgz")
+}
+
+// checkIntegrity checks a subresource integrity string, the format npm uses
+// for the integrity of a package
+func checkIntegrity(reader io.Reader, integrity string) error {
+	algorithm, expected, ok := strings.Cut(integrity, "-")
+	if !ok {
+		return fmt.Errorf("%w: invalid integrity %s", ErrProviderIntegrity, integrity)
+	}
+	var h hash.Hash
+	switch algorithm {
+	case "sha512":
+		h = sha512.New()
+	case "sha256":
+		h = sha256.New()
+	case "sha1":
+		h = sha1.New()
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %s", ErrProviderIntegrity, algorithm)
+	}
+	_, err := io.Copy(h, reader)
+	if err != nil {
+		return err
+	}
+	actual := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	if actual != expected {
+		return fmt.Errorf("%w: expected %s, got %s-%s", ErrProviderIntegrity, integrity, algorithm, actual)
+	}
+	return nil
+}
+
+// installedVersion returns the version of a package in the platform
+// node_modules, or "" if it isn't installed
+func (p *Project) installedVersion(pkg string) string {
+	data, err := os.ReadFile(filepath.Join(p.PathPlatformDir(), "node_modules", pkg, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var parsed struct {
+		Version string `json:"version"`
+	}
+	if json.Unmarshal(data, &parsed) != nil {
+		return ""
+	}
+	return parsed.Version
+}
@@ -0,0 +1,139 @@
+package project
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sst/ion/pkg/flag"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	sum := sha512.Sum512([]byte("package"))
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+	if err := checkIntegrity(strings.NewReader("package"), integrity); err != nil {
+		t.Fatal(err)
+	}
+	err := checkIntegrity(strings.NewReader("tampered"), integrity)
+	if !errors.Is(err, ErrProviderIntegrity) {
+		t.Fatalf("expected ErrProviderIntegrity, got %v", err)
+	}
+	err = checkIntegrity(strings.NewReader("package"), "md5-abc")
+	if !errors.Is(err, ErrProviderIntegrity) {
+		t.Fatalf("expected unsupported algorithm to fail, got %v", err)
+	}
+}
+
+func TestCheckLock(t *testing.T) {
+	p := &Project{
+		app: &App{
+			Providers: map[string]interface{}{
+				"aws":        map[string]interface{}{"version": "6.52.0"},
+				"cloudflare": map[string]interface{}{},
+				"random":     map[string]interface{}{},
+			},
+		},
+		lock: ProviderLock{
+			{Name: "aws", Version: "6.51.0", Integrity: "sha512-a"},
+			{Name: "cloudflare", Version: "5.37.1", Integrity: "sha512-b"},
+			{Name: "tls", Version: "5.0.1"},
+		},
+	}
+	var mismatch *LockMismatchError
+	err := p.checkLock()
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrLockMismatch) {
+		t.Fatalf("expected LockMismatchError, got %v", err)
+	}
+	expected := []string{
+		"aws is 6.52.0 in the config but 6.51.0 in the lock",
+		"random is in the config but not in the lock",
+		"tls is in the lock but not in the config",
+		"tls has no integrity in the lock",
+	}
+	if strings.Join(mismatch.Mismatches, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected mismatches:\n%s", strings.Join(mismatch.Mismatches, "\n"))
+	}
+
+	p.app.Providers = map[string]interface{}{
+		"aws":        map[string]interface{}{"version": "6.51.0"},
+		"cloudflare": map[string]interface{}{},
+	}
+	p.lock = p.lock[:2]
+	if err := p.checkLock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPathProviderLock(t *testing.T) {
+	for config, lock := range map[string]string{
+		"/app/sst.config.ts":     "/app/sst.lock.json",
+		"/app/sst.api.config.ts": "/app/sst.api.lock.json",
+	} {
+		p := Project{config: config, root: "/app"}
+		if p.PathProviderLock() != lock {
+			t.Fatalf("expected %s, got %s", lock, p.PathProviderLock())
+		}
+	}
+}
+
+func TestVerifyLockInstallsVerifiedTarball(t *testing.T) {
+	tarball := []byte("package")
+	sum := sha512.Sum512(tarball)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	p := &Project{
+		config: filepath.Join(dir, "sst.config.ts"),
+		root:   dir,
+		lock: ProviderLock{{
+			Name:      "aws",
+			Package:   "@pulumi/aws",
+			Version:   "6.52.0",
+			Resolved:  server.URL,
+			Integrity: "sha512-" + base64.StdEncoding.EncodeToString(sum[:]),
+		}},
+	}
+	if err := p.verifyLock(); err != nil {
+		t.Fatal(err)
+	}
+	path := p.pathProviderTarball(p.lock[0])
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "package" {
+		t.Fatalf("expected the verified tarball to be kept, got %q %v", data, err)
+	}
+
+	os.WriteFile(filepath.Join(p.PathPlatformDir(), "package.json"), []byte(`{"dependencies":{}}`), 0644)
+	flag.SST_FROZEN = true
+	defer func() { flag.SST_FROZEN = false }()
+	if err := p.writePackageJson(); err != nil {
+		t.Fatal(err)
+	}
+	var packageJson struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	data, _ = os.ReadFile(filepath.Join(p.PathPlatformDir(), "package.json"))
+	json.Unmarshal(data, &packageJson)
+	if packageJson.Dependencies["@pulumi/aws"] != "file:"+filepath.ToSlash(path) {
+		t.Fatalf("expected the verified tarball to be installed, got %v", packageJson.Dependencies)
+	}
+
+	tarball = []byte("tampered")
+	os.Remove(path)
+	err = p.verifyLock()
+	if !errors.Is(err, ErrProviderIntegrity) {
+		t.Fatalf("expected ErrProviderIntegrity, got %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 0 {
+		t.Fatalf("expected the tampered tarball to be removed, got %v", entries)
+	}
+}
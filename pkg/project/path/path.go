@@ -1,9 +1,37 @@
 package path
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
 
+// ConfigNames are the config files that are discovered automatically, in
+// order of preference
+var ConfigNames = []string{"sst.config.ts", "sst.config.mjs", "sst.config.js"}
+
+// ResolveWorkingDir returns the .sst directory of a config. A config with a
+// custom name gets its own directory inside it, so apps that live in the same
+// directory don't share state.
 func ResolveWorkingDir(cfgPath string) string {
-	return filepath.Join(ResolveRootDir(cfgPath), ".sst")
+	workingDir := filepath.Join(ResolveRootDir(cfgPath), ".sst")
+	if name := ResolveAppDir(cfgPath); name != "" {
+		return filepath.Join(workingDir, "apps", name)
+	}
+	return workingDir
+}
+
+// ResolveAppDir returns the name of the directory for a config with a custom
+// name, like "api" for "sst.api.config.ts", or "" for the default names
+func ResolveAppDir(cfgPath string) string {
+	base := filepath.Base(cfgPath)
+	if slices.Contains(ConfigNames, base) {
+		return ""
+	}
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	name = strings.TrimSuffix(name, ".config")
+	name = strings.TrimPrefix(name, "sst.")
+	return name
 }
 
 func ResolvePlatformDir(cfgPath string) string {
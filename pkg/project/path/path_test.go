@@ -0,0 +1,23 @@
+package path
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveWorkingDir(t *testing.T) {
+	cases := map[string]string{
+		"/repo/sst.config.ts":      "/repo/.sst",
+		"/repo/sst.config.mjs":     "/repo/.sst",
+		"/repo/sst.config.js":      "/repo/.sst",
+		"/repo/sst.api.config.ts":  "/repo/.sst/apps/api",
+		"/repo/web.config.mjs":     "/repo/.sst/apps/web",
+		"/repo/apps/sst.config.ts": "/repo/apps/.sst",
+	}
+	for cfgPath, expected := range cases {
+		actual := ResolveWorkingDir(filepath.FromSlash(cfgPath))
+		if actual != filepath.FromSlash(expected) {
+			t.Errorf("%s: expected %s, got %s", cfgPath, expected, actual)
+		}
+	}
+}
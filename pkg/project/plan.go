@@ -0,0 +1,186 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const PlanVersion = 1
+
+// Plan is the set of operations `sst diff --out` saw, so `sst deploy --plan`
+// can refuse to run anything that wasn't reviewed.
+type Plan struct {
+	Version    int             `json:"version"`
+	App        string          `json:"app"`
+	Stage      string          `json:"stage"`
+	Created    string          `json:"created"`
+	Target     []string        `json:"target,omitempty"`
+	ConfigHash string          `json:"configHash"`
+	Operations []PlanOperation `json:"operations"`
+}
+
+type PlanOperation struct {
+	URN  string         `json:"urn"`
+	Type string         `json:"type"`
+	Op   apitype.OpType `json:"op"`
+}
+
+var ErrPlanMismatch = fmt.Errorf("operations do not match the plan")
+var ErrPlanConfigChanged = fmt.Errorf("config changed since the plan was created")
+
+type PlanMismatchError struct {
+	Missing    []PlanOperation
+	Unexpected []PlanOperation
+}
+
+func (e *PlanMismatchError) Error() string {
+	lines := []string{ErrPlanMismatch.Error()}
+	for _, op := range e.Unexpected {
+		lines = append(lines, fmt.Sprintf("  not in plan: %s %s", op.Op, op.URN))
+	}
+	for _, op := range e.Missing {
+		lines = append(lines, fmt.Sprintf("  not happening: %s %s", op.Op, op.URN))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *PlanMismatchError) Is(target error) bool {
+	return target == ErrPlanMismatch
+}
+
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	err = json.Unmarshal(data, &plan)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d", plan.Version)
+	}
+	return &plan, nil
+}
+
+func (p *Plan) Write(path string) error {
+	sort.Slice(p.Operations, func(i, j int) bool {
+		return p.Operations[i].URN < p.Operations[j].URN
+	})
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Compare returns a PlanMismatchError if the operations aren't exactly the
+// ones in the plan
+func (p *Plan) Compare(operations []PlanOperation) error {
+	planned := map[PlanOperation]bool{}
+	for _, op := range p.Operations {
+		planned[op] = true
+	}
+	mismatch := &PlanMismatchError{}
+	seen := map[PlanOperation]bool{}
+	for _, op := range operations {
+		seen[op] = true
+		if !planned[op] {
+			mismatch.Unexpected = append(mismatch.Unexpected, op)
+		}
+	}
+	for _, op := range p.Operations {
+		if !seen[op] {
+			mismatch.Missing = append(mismatch.Missing, op)
+		}
+	}
+	if len(mismatch.Missing) > 0 || len(mismatch.Unexpected) > 0 {
+		return mismatch
+	}
+	return nil
+}
+
+// isPlannedOp filters out the operations that don't change anything
+func isPlannedOp(op PlanOperation) bool {
+	if op.Type == "pulumi:pulumi:Stack" {
+		return false
+	}
+	return op.Op != apitype.OpSame && op.Op != apitype.OpRead
+}
+
+// hashConfig hashes everything the config is evaluated from: the source files
+// that were bundled, the app and the provider config. The files are absolute
+// paths but are named relative to the root, so the hash doesn't depend on
+// where the project is checked out.
+func hashConfig(root string, files []string, app []byte, config auto.ConfigMap) (string, error) {
+	hash := sha256.New()
+	err := hashInputs(hash, files, func(file string) string {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return filepath.ToSlash(file)
+		}
+		return filepath.ToSlash(rel)
+	})
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(hash, "app:%s\n", app)
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(hash, "config:%s=%s\n", key, config[key].Value)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func planOperation(event *apitype.ResourcePreEvent) PlanOperation {
+	return PlanOperation{
+		URN:  event.Metadata.URN,
+		Type: event.Metadata.Type,
+		Op:   event.Metadata.Op,
+	}
+}
+
+// previewResources runs a preview without publishing any events and returns
+// the resources it would touch
+func previewResources(preview func(stream chan<- events.EngineEvent) error) ([]*apitype.ResourcePreEvent, error) {
+	stream := make(chan events.EngineEvent)
+	done := make(chan []*apitype.ResourcePreEvent)
+	go func() {
+		resources := []*apitype.ResourcePreEvent{}
+		for event := range stream {
+			if event.ResourcePreEvent != nil {
+				resources = append(resources, event.ResourcePreEvent)
+			}
+		}
+		done <- resources
+	}()
+	err := preview(stream)
+	resources := <-done
+	return resources, err
+}
+
+func plannedOperations(resources []*apitype.ResourcePreEvent) []PlanOperation {
+	operations := []PlanOperation{}
+	for _, resource := range resources {
+		op := planOperation(resource)
+		if isPlannedOp(op) {
+			operations = append(operations, op)
+		}
+	}
+	return operations
+}
@@ -0,0 +1,77 @@
+package project
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+)
+
+func applyPreviewOptions(input *StackInput) optpreview.Options {
+	var opts optpreview.Options
+	for _, opt := range previewOptions(input, nil, io.Discard, io.Discard) {
+		opt.ApplyOption(&opts)
+	}
+	return opts
+}
+
+func TestPreviewOptions(t *testing.T) {
+	opts := applyPreviewOptions(&StackInput{Target: []string{"urn:a"}})
+	if len(opts.Target) != 1 || opts.Target[0] != "urn:a" {
+		t.Fatalf("expected the target to be set, got %v", opts.Target)
+	}
+	if !opts.TargetDependents {
+		t.Fatal("expected the dependents of a target to be previewed so a saved plan matches deploy")
+	}
+	opts = applyPreviewOptions(&StackInput{Target: []string{"urn:a"}, Exclude: []string{"urn:b"}})
+	if opts.TargetDependents {
+		t.Fatal("expected no dependents to be targeted when excluding")
+	}
+}
+
+func TestHashConfig(t *testing.T) {
+	write := func(root string) []string {
+		os.MkdirAll(filepath.Join(root, "infra"), 0755)
+		os.WriteFile(filepath.Join(root, "sst.config.ts"), []byte("config"), 0644)
+		os.WriteFile(filepath.Join(root, "infra", "api.ts"), []byte("api"), 0644)
+		return []string{
+			filepath.Join(root, "infra", "api.ts"),
+			filepath.Join(root, "sst.config.ts"),
+		}
+	}
+	config := auto.ConfigMap{"aws:region": auto.ConfigValue{Value: "us-east-1"}}
+
+	first := t.TempDir()
+	firstFiles := write(first)
+	a, err := hashConfig(first, firstFiles, []byte("app"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := t.TempDir()
+	secondFiles := write(second)
+	b, err := hashConfig(second, []string{secondFiles[1], secondFiles[0]}, []byte("app"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("expected the same hash for the same project in another directory")
+	}
+
+	os.WriteFile(firstFiles[0], []byte("changed"), 0644)
+	c, err := hashConfig(first, firstFiles, []byte("app"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == c {
+		t.Fatal("expected a changed input to change the hash")
+	}
+
+	os.Remove(firstFiles[0])
+	_, err = hashConfig(first, firstFiles, []byte("app"), config)
+	if err == nil {
+		t.Fatal("expected a missing input to fail")
+	}
+}
@@ -0,0 +1,238 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/bus"
+)
+
+// Policy is a set of rules every resource in the app is checked against
+// before it's deployed. It's read from sst.policy.json next to the config.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule checks a single property of the inputs of every resource of the
+// given types. Types can end in * to match a prefix.
+type PolicyRule struct {
+	Name      string        `json:"name"`
+	Message   string        `json:"message"`
+	Help      []string      `json:"help"`
+	Types     []string      `json:"types"`
+	Property  string        `json:"property"`
+	Required  bool          `json:"required"`
+	Equals    interface{}   `json:"equals"`
+	NotEquals interface{}   `json:"notEquals"`
+	OneOf     []interface{} `json:"oneOf"`
+	// Level is either "error", which fails the run, or "warn"
+	Level string `json:"level"`
+}
+
+type PolicyViolation struct {
+	Rule *PolicyRule
+	URN  string
+}
+
+type PolicyWarningEvent struct {
+	Rule    string
+	URN     string
+	Message string
+	Help    []string
+}
+
+// pulumi uses these to mark values during a preview
+const (
+	policyUnknownValue = "04da6b54-80e4-46f7-96ec-b56ff0331ba9"
+	policySecretSig    = "4dabf18193072939515e22adb298388d"
+)
+
+func (p Project) PathPolicy() string {
+	return filepath.Join(p.PathRoot(), "sst.policy.json")
+}
+
+// ReadPolicy returns nil if there is no policy file
+func ReadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	err = json.Unmarshal(data, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("invalid policy file %s: rule %d has no name", path, i)
+		}
+		if rule.Property == "" {
+			return nil, fmt.Errorf("invalid policy file %s: rule %q has no property", path, rule.Name)
+		}
+		if rule.Level == "" {
+			rule.Level = "error"
+		}
+		if rule.Level != "error" && rule.Level != "warn" {
+			return nil, fmt.Errorf("invalid policy file %s: rule %q has an invalid level %q", path, rule.Name, rule.Level)
+		}
+	}
+	return &policy, nil
+}
+
+// Check returns every rule the resource being deployed violates
+func (p *Policy) Check(event *apitype.ResourcePreEvent) []PolicyViolation {
+	metadata := event.Metadata
+	if metadata.New == nil || metadata.Op == apitype.OpDelete || metadata.Op == apitype.OpDeleteReplaced {
+		return nil
+	}
+	violations := []PolicyViolation{}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.matches(metadata.Type) {
+			continue
+		}
+		value, exists := lookupProperty(metadata.New.Inputs, rule.Property)
+		if !rule.allows(value, exists) {
+			violations = append(violations, PolicyViolation{Rule: rule, URN: metadata.URN})
+		}
+	}
+	return violations
+}
+
+func (r *PolicyRule) matches(kind string) bool {
+	if len(r.Types) == 0 {
+		return true
+	}
+	for _, item := range r.Types {
+		if prefix, ok := strings.CutSuffix(item, "*"); ok && strings.HasPrefix(kind, prefix) {
+			return true
+		}
+		if item == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PolicyRule) allows(value interface{}, exists bool) bool {
+	if !exists || isEmptyValue(value) {
+		return !r.Required
+	}
+	// values that aren't known until the deploy can only be checked for
+	// being set
+	if value == policyUnknownValue {
+		return true
+	}
+	if secret, ok := value.(map[string]interface{}); ok && secret[policySecretSig] != nil {
+		return true
+	}
+	if r.Equals != nil && !policyEqual(value, r.Equals) {
+		return false
+	}
+	if r.NotEquals != nil && policyEqual(value, r.NotEquals) {
+		return false
+	}
+	if len(r.OneOf) > 0 && !slices.ContainsFunc(r.OneOf, func(item interface{}) bool {
+		return policyEqual(value, item)
+	}) {
+		return false
+	}
+	return true
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
+// policyEqual compares the values by their JSON encoding so numbers and
+// nested values from the policy file match the ones from the engine
+func policyEqual(a, b interface{}) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(left) == string(right)
+}
+
+// lookupProperty walks a dotted path like "tags.team" or "rules.0.action"
+func lookupProperty(inputs map[string]interface{}, property string) (interface{}, bool) {
+	var current interface{} = inputs
+	for _, part := range strings.Split(property, ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			current = value[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func (v PolicyViolation) Error() Error {
+	message := fmt.Sprintf("Policy %q failed", v.Rule.Name)
+	if v.Rule.Message != "" {
+		message += ": " + v.Rule.Message
+	}
+	return Error{
+		Message: message,
+		URN:     v.URN,
+		Help:    v.Rule.Help,
+	}
+}
+
+// applyPolicy publishes the warnings and returns the violations that should
+// fail the run
+func applyPolicy(policy *Policy, event *apitype.ResourcePreEvent) []Error {
+	if policy == nil {
+		return nil
+	}
+	failed := []Error{}
+	for _, violation := range policy.Check(event) {
+		if violation.Rule.Level == "warn" {
+			err := violation.Error()
+			bus.Publish(&PolicyWarningEvent{
+				Rule:    violation.Rule.Name,
+				URN:     err.URN,
+				Message: err.Message,
+				Help:    err.Help,
+			})
+			continue
+		}
+		failed = append(failed, violation.Error())
+	}
+	return failed
+}
@@ -0,0 +1,105 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sst.policy.json")
+	err := os.WriteFile(path, []byte(`{
+		"rules": [
+			{
+				"name": "bucket-encryption",
+				"types": ["aws:s3/bucketV2:BucketV2"],
+				"property": "serverSideEncryptionConfigurations",
+				"required": true
+			},
+			{
+				"name": "private-function-urls",
+				"types": ["aws:lambda/functionUrl:FunctionUrl"],
+				"property": "authorizationType",
+				"notEquals": "NONE"
+			},
+			{
+				"name": "team-tag",
+				"types": ["aws:s3/*"],
+				"property": "tags.team",
+				"required": true,
+				"level": "warn"
+			}
+		]
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := ReadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource := func(kind string, op apitype.OpType, inputs map[string]interface{}) *apitype.ResourcePreEvent {
+		return &apitype.ResourcePreEvent{
+			Metadata: apitype.StepEventMetadata{
+				URN:  "urn:pulumi:dev::app::" + kind + "::Resource",
+				Type: kind,
+				Op:   op,
+				New:  &apitype.StepEventStateMetadata{Inputs: inputs},
+			},
+		}
+	}
+	cases := []struct {
+		name     string
+		event    *apitype.ResourcePreEvent
+		expected []string
+	}{
+		{
+			"unencrypted bucket without tags",
+			resource("aws:s3/bucketV2:BucketV2", apitype.OpCreate, map[string]interface{}{}),
+			[]string{"bucket-encryption", "team-tag"},
+		},
+		{
+			"encrypted bucket with tags",
+			resource("aws:s3/bucketV2:BucketV2", apitype.OpUpdate, map[string]interface{}{
+				"serverSideEncryptionConfigurations": []interface{}{map[string]interface{}{}},
+				"tags":                               map[string]interface{}{"team": "platform"},
+			}),
+			[]string{},
+		},
+		{
+			"public function url",
+			resource("aws:lambda/functionUrl:FunctionUrl", apitype.OpCreate, map[string]interface{}{
+				"authorizationType": "NONE",
+			}),
+			[]string{"private-function-urls"},
+		},
+		{
+			"unknown function url auth",
+			resource("aws:lambda/functionUrl:FunctionUrl", apitype.OpCreate, map[string]interface{}{
+				"authorizationType": policyUnknownValue,
+			}),
+			[]string{},
+		},
+		{
+			"deleted bucket",
+			resource("aws:s3/bucketV2:BucketV2", apitype.OpDelete, map[string]interface{}{}),
+			[]string{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := policy.Check(tc.event)
+			if len(violations) != len(tc.expected) {
+				t.Fatalf("expected %v, got %d violations", tc.expected, len(violations))
+			}
+			for i, violation := range violations {
+				if violation.Rule.Name != tc.expected[i] {
+					t.Fatalf("expected %v, got %s", tc.expected, violation.Rule.Name)
+				}
+			}
+		})
+	}
+}
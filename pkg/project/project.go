@@ -17,6 +17,7 @@ import (
 	"github.com/sst/ion/pkg/flag"
 	"github.com/sst/ion/pkg/js"
 	"github.com/sst/ion/pkg/process"
+	projectpath "github.com/sst/ion/pkg/project/path"
 	"github.com/sst/ion/pkg/project/provider"
 	"github.com/sst/ion/pkg/runtime"
 	"github.com/sst/ion/pkg/runtime/node"
@@ -31,6 +32,7 @@ type App struct {
 	Providers map[string]interface{} `json:"providers"`
 	Home      string                 `json:"home"`
 	Version   string                 `json:"version"`
+	Protect   []string               `json:"protect"`
 	// Deprecated: Backend is now Home
 	Backend string `json:"backend"`
 	// Deprecated: RemovalPolicy is now Removal
@@ -46,35 +48,65 @@ type Project struct {
 	home            provider.Home
 	env             map[string]string
 	loadedProviders map[string]provider.Provider
+	stopHeartbeat   func()
+	lockLost        chan struct{}
 	Runtime         *runtime.Collection
 }
 
+var ErrConfigNotFound = fmt.Errorf("config not found")
+
+// Discover returns the config set with SST_CONFIG or --config, or searches up
+// from the current directory for one of the default config files
 func Discover() (string, error) {
-	cwd, err := os.Getwd()
+	cfgPath, err := discover()
 	if err != nil {
 		return "", err
 	}
-	cfgPath, err := fs.FindUp(cwd, "sst.config.ts")
+	err = os.MkdirAll(ResolveWorkingDir(cfgPath), 0755)
 	if err != nil {
 		return "", err
 	}
-	err = os.MkdirAll(ResolveWorkingDir(cfgPath), 0755)
+	return cfgPath, nil
+}
+
+func discover() (string, error) {
+	if flag.SST_CONFIG != "" {
+		cfgPath, err := filepath.Abs(flag.SST_CONFIG)
+		if err != nil {
+			return "", err
+		}
+		if !fs.Exists(cfgPath) {
+			return "", fmt.Errorf("%w: %s", ErrConfigNotFound, cfgPath)
+		}
+		return cfgPath, nil
+	}
+	cwd, err := os.Getwd()
 	if err != nil {
 		return "", err
 	}
-	return cfgPath, nil
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		for _, name := range projectpath.ConfigNames {
+			cfgPath := filepath.Join(dir, name)
+			if fs.Exists(cfgPath) {
+				return cfgPath, nil
+			}
+		}
+		if dir == filepath.Dir(dir) {
+			return "", ErrConfigNotFound
+		}
+	}
 }
 
 func ResolveWorkingDir(cfgPath string) string {
-	return filepath.Join(filepath.Dir(cfgPath), ".sst")
+	return projectpath.ResolveWorkingDir(cfgPath)
 }
 
 func ResolvePlatformDir(cfgPath string) string {
-	return filepath.Join(ResolveWorkingDir(cfgPath), "platform")
+	return projectpath.ResolvePlatformDir(cfgPath)
 }
 
 func ResolveLogDir(cfgPath string) string {
-	return filepath.Join(ResolveWorkingDir(cfgPath), "log")
+	return projectpath.ResolveLogDir(cfgPath)
 }
 
 type ProjectConfig struct {
@@ -120,45 +152,18 @@ func New(input *ProjectConfig) (*Project, error) {
 		if !os.IsNotExist(err) {
 			return nil, err
 		}
-		err := os.Mkdir(tmp, 0755)
+		err := os.MkdirAll(tmp, 0755)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	inputBytes, err := json.Marshal(map[string]string{
-		"stage": input.Stage,
-	})
-	buildResult, err := js.Build(
-		js.EvalOptions{
-			Dir:    proj.PathRoot(),
-			Banner: `function $config(input) { return input }`,
-			Define: map[string]string{
-				"$input": string(inputBytes),
-			},
-			Code: fmt.Sprintf(`
-import mod from '%s';
-if (mod.stacks || mod.config) {
-  console.log("~v2")
-  process.exit(0)
-}
-console.log("~j" + JSON.stringify(mod.app({
-  stage: $input.stage || undefined,
-})))`,
-				input.Config),
-		},
-	)
-	if err != nil {
-		return nil, fmt.Errorf("%w%s", ErrBuildFailed, err)
-	}
-	defer js.Cleanup(buildResult)
-
-	slog.Info("evaluating config")
-	node := process.Command("node", "--no-warnings", string(buildResult.OutputFiles[1].Path))
-	output, err := node.CombinedOutput()
-	slog.Info("config evaluated")
-	if err != nil {
-		return nil, fmt.Errorf("Error evaluating config: %w\n%s", err, output)
+	output, ok := readAppCache(proj.pathAppCache(input.Stage), input.Version, input.Stage)
+	if !ok {
+		output, err = proj.evaluateApp(input)
+		if err != nil {
+			return nil, err
+		}
 	}
 	scanner := bufio.NewScanner(bytes.NewReader(output))
 	for scanner.Scan() {
@@ -220,7 +225,7 @@ console.log("~j" + JSON.stringify(mod.app({
 				return nil, util.NewReadableError(nil, `You must specify a "home" provider in the project configuration file.`)
 			}
 
-			if _, ok := proj.app.Providers[proj.app.Home]; !ok && proj.app.Home != "local" {
+			if _, ok := proj.app.Providers[proj.app.Home]; !ok && proj.app.Home != "local" && proj.app.Home != "s3" {
 				proj.app.Providers[proj.app.Home] = map[string]interface{}{}
 			}
 
@@ -264,6 +269,50 @@ console.log("~j" + JSON.stringify(mod.app({
 	return proj, nil
 }
 
+// evaluateApp runs the app function in the config and caches the output
+func (proj *Project) evaluateApp(input *ProjectConfig) ([]byte, error) {
+	inputBytes, err := json.Marshal(map[string]string{
+		"stage": input.Stage,
+	})
+	buildResult, err := js.Build(
+		js.EvalOptions{
+			Dir:        proj.PathRoot(),
+			WorkingDir: proj.PathWorkingDir(),
+			Banner:     `function $config(input) { return input }`,
+			Define: map[string]string{
+				"$input": string(inputBytes),
+			},
+			Code: fmt.Sprintf(`
+import mod from '%s';
+if (mod.stacks || mod.config) {
+  console.log("~v2")
+  process.exit(0)
+}
+console.log("~j" + JSON.stringify(mod.app({
+  stage: $input.stage || undefined,
+})))`,
+				input.Config),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w%s", ErrBuildFailed, err)
+	}
+	defer js.Cleanup(buildResult)
+
+	slog.Info("evaluating config")
+	node := process.Command("node", "--no-warnings", string(buildResult.OutputFiles[1].Path))
+	output, err := node.CombinedOutput()
+	slog.Info("config evaluated")
+	if err != nil {
+		return nil, fmt.Errorf("Error evaluating config: %w\n%s", err, output)
+	}
+	err = writeAppCache(proj.pathAppCache(input.Stage), input.Version, input.Stage, buildResult.Metafile, output)
+	if err != nil {
+		slog.Info("could not cache config", "err", err)
+	}
+	return output, nil
+}
+
 func (proj *Project) LoadHome() error {
 	slog.Info("loading home")
 	loadedProviders := make(map[string]provider.Provider)
@@ -293,26 +342,59 @@ func (proj *Project) LoadHome() error {
 		loadedProviders[key] = match
 	}
 
+	proj.loadedProviders = loadedProviders
+	home, err := proj.NewHome(proj.app.Home)
+	if err != nil {
+		return err
+	}
+	proj.home = home
+	return nil
+}
+
+// NewHome initializes and bootstraps the home with the given name. Providers
+// that were not configured in the app are initialized with default arguments.
+func (proj *Project) NewHome(name string) (provider.Home, error) {
 	var home provider.Home
 
-	switch proj.app.Home {
+	switch name {
 	case "local":
 		home = provider.NewLocalHome()
+	case "s3":
+		home = provider.NewS3Home()
 	case "aws":
-		home = provider.NewAwsHome(loadedProviders["aws"].(*provider.AwsProvider))
+		match, err := proj.homeProvider(name, func() provider.Provider { return provider.NewAwsProvider() })
+		if err != nil {
+			return nil, err
+		}
+		home = provider.NewAwsHome(match.(*provider.AwsProvider))
 	case "cloudflare":
-		home = provider.NewCloudflareHome(loadedProviders["cloudflare"].(*provider.CloudflareProvider))
+		match, err := proj.homeProvider(name, func() provider.Provider { return &provider.CloudflareProvider{} })
+		if err != nil {
+			return nil, err
+		}
+		home = provider.NewCloudflareHome(match.(*provider.CloudflareProvider))
 	default:
-		return fmt.Errorf("Home provider %s is invalid", proj.app.Home)
+		return nil, fmt.Errorf("Home provider %s is invalid", name)
 	}
 
 	err := home.Bootstrap()
 	if err != nil {
-		return fmt.Errorf("Error initializing %s:\n   %w", proj.app.Home, err)
+		return nil, fmt.Errorf("Error initializing %s:\n   %w", name, err)
 	}
-	proj.home = home
-	proj.loadedProviders = loadedProviders
-	return nil
+	return home, nil
+}
+
+func (proj *Project) homeProvider(name string, create func() provider.Provider) (provider.Provider, error) {
+	if match, ok := proj.loadedProviders[name]; ok {
+		return match, nil
+	}
+	match := create()
+	err := match.Init(proj.app.Name, proj.app.Stage, map[string]interface{}{})
+	if err != nil {
+		return nil, util.NewReadableError(err, name+": "+err.Error())
+	}
+	proj.loadedProviders[name] = match
+	return match, nil
 }
 
 func (p Project) getPath(path ...string) string {
@@ -321,7 +403,7 @@ func (p Project) getPath(path ...string) string {
 }
 
 func (p Project) PathWorkingDir() string {
-	return filepath.Join(p.root, ".sst")
+	return projectpath.ResolveWorkingDir(p.config)
 }
 
 func (p Project) PathPlatformDir() string {
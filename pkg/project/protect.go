@@ -0,0 +1,65 @@
+package project
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+var ErrProtectedResource = fmt.Errorf("protected resources would be destroyed")
+
+type ProtectedResourceError struct {
+	Operations []PlanOperation
+}
+
+func (e *ProtectedResourceError) Error() string {
+	lines := []string{ErrProtectedResource.Error()}
+	for _, op := range e.Operations {
+		lines = append(lines, fmt.Sprintf("  %s %s", op.Op, op.URN))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ProtectedResourceError) Is(target error) bool {
+	return target == ErrProtectedResource
+}
+
+// isDestructiveOp is true for every step that removes the underlying resource,
+// including the steps of a replacement
+func isDestructiveOp(op apitype.OpType) bool {
+	switch op {
+	case apitype.OpDelete,
+		apitype.OpDeleteReplaced,
+		apitype.OpReplace,
+		apitype.OpCreateReplacement:
+		return true
+	}
+	return false
+}
+
+// checkProtected returns a ProtectedResourceError if any of the operations
+// destroy a resource that matches the protect list by URN or type, unless its
+// URN was explicitly allowed
+func checkProtected(protect []string, allow []string, operations []PlanOperation) error {
+	blocked := []PlanOperation{}
+	seen := map[string]bool{}
+	for _, op := range operations {
+		if !isDestructiveOp(op.Op) || seen[op.URN] {
+			continue
+		}
+		if !slices.Contains(protect, op.URN) && !slices.Contains(protect, op.Type) {
+			continue
+		}
+		if slices.Contains(allow, op.URN) {
+			continue
+		}
+		seen[op.URN] = true
+		blocked = append(blocked, op)
+	}
+	if len(blocked) > 0 {
+		return &ProtectedResourceError{Operations: blocked}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package project
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestCheckProtected(t *testing.T) {
+	database := "urn:pulumi:production::app::sst:aws:Postgres$aws:rds/instance:Instance::Database"
+	bucket := "urn:pulumi:production::app::sst:aws:Bucket$aws:s3/bucketV2:BucketV2::Assets"
+	operations := []PlanOperation{
+		{URN: database, Type: "aws:rds/instance:Instance", Op: apitype.OpCreateReplacement},
+		{URN: database, Type: "aws:rds/instance:Instance", Op: apitype.OpReplace},
+		{URN: database, Type: "aws:rds/instance:Instance", Op: apitype.OpDeleteReplaced},
+		{URN: bucket, Type: "aws:s3/bucketV2:BucketV2", Op: apitype.OpUpdate},
+	}
+
+	err := checkProtected([]string{"aws:rds/instance:Instance", bucket}, nil, operations)
+	var protected *ProtectedResourceError
+	if !errors.As(err, &protected) || !errors.Is(err, ErrProtectedResource) {
+		t.Fatalf("expected a protected resource error, got %v", err)
+	}
+	if len(protected.Operations) != 1 || protected.Operations[0].URN != database {
+		t.Fatalf("expected only the database to be blocked, got %v", protected.Operations)
+	}
+
+	err = checkProtected([]string{"aws:rds/instance:Instance"}, []string{database}, operations)
+	if err != nil {
+		t.Fatalf("expected allowed resource to pass, got %v", err)
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/aws/smithy-go"
 	"github.com/sst/ion/internal/util"
 
 	ecrTypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
@@ -521,25 +520,7 @@ func (a *AwsHome) getData(key, app, stage string) (io.Reader, error) {
 		return nil, err
 	}
 	s3Client := s3.NewFromConfig(a.provider.config)
-
-	result, err := s3Client.GetObject(context.TODO(), &s3.GetObjectInput{
-		Bucket: aws.String(bootstrap.State),
-		Key:    aws.String(a.pathForData(key, app, stage)),
-	})
-	if err != nil {
-		var apiErr smithy.APIError
-		if errors.As(err, &apiErr) {
-			if apiErr.ErrorCode() == "NoSuchBucket" {
-				return nil, ErrBucketMissing
-			}
-		}
-		var nsk *s3types.NoSuchKey
-		if errors.As(err, &nsk) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return result.Body, nil
+	return s3GetData(s3Client, bootstrap.State, a.pathForData(key, app, stage))
 }
 
 func (a *AwsHome) putData(key, app, stage string, data io.Reader) error {
@@ -548,36 +529,52 @@ func (a *AwsHome) putData(key, app, stage string, data io.Reader) error {
 		return err
 	}
 	s3Client := s3.NewFromConfig(a.provider.config)
+	return s3PutData(s3Client, bootstrap.State, a.pathForData(key, app, stage), data)
+}
 
-	_, err = s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket:      aws.String(bootstrap.State),
-		Key:         aws.String(a.pathForData(key, app, stage)),
-		Body:        data,
-		ContentType: aws.String("application/json"),
-	})
+func (a *AwsHome) createData(key, app, stage string, data io.Reader) error {
+	bootstrap, err := a.provider.Bootstrap(a.provider.config.Region)
 	if err != nil {
 		return err
 	}
+	s3Client := s3.NewFromConfig(a.provider.config)
+	return s3CreateData(s3Client, bootstrap.State, a.pathForData(key, app, stage), data)
+}
 
-	return nil
+func (a *AwsHome) getDataVersion(key, app, stage string) (io.Reader, string, error) {
+	bootstrap, err := a.provider.Bootstrap(a.provider.config.Region)
+	if err != nil {
+		return nil, "", err
+	}
+	s3Client := s3.NewFromConfig(a.provider.config)
+	return s3GetDataVersion(s3Client, bootstrap.State, a.pathForData(key, app, stage))
 }
 
-func (a *AwsHome) removeData(key, app, stage string) error {
+func (a *AwsHome) replaceData(key, app, stage, version string, data io.Reader) error {
 	bootstrap, err := a.provider.Bootstrap(a.provider.config.Region)
 	if err != nil {
 		return err
 	}
 	s3Client := s3.NewFromConfig(a.provider.config)
+	return s3ReplaceData(s3Client, bootstrap.State, a.pathForData(key, app, stage), version, data)
+}
 
-	_, err = s3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-		Bucket: aws.String(bootstrap.State),
-		Key:    aws.String(a.pathForData(key, app, stage)),
-	})
+func (a *AwsHome) removeData(key, app, stage string) error {
+	bootstrap, err := a.provider.Bootstrap(a.provider.config.Region)
 	if err != nil {
 		return err
 	}
+	s3Client := s3.NewFromConfig(a.provider.config)
+	return s3RemoveData(s3Client, bootstrap.State, a.pathForData(key, app, stage))
+}
 
-	return nil
+func (a *AwsHome) listData(key, app, stage string) ([]string, error) {
+	bootstrap, err := a.provider.Bootstrap(a.provider.config.Region)
+	if err != nil {
+		return nil, err
+	}
+	s3Client := s3.NewFromConfig(a.provider.config)
+	return s3ListData(s3Client, bootstrap.State, path.Join(key, app, stage)+"/")
 }
 
 func (a *AwsHome) getPassphrase(app string, stage string) (string, error) {
@@ -611,6 +608,19 @@ func (a *AwsHome) setPassphrase(app, stage, passphrase string) error {
 	return err
 }
 
+func (a *AwsHome) updatePassphrase(app, stage, passphrase string) error {
+	ssmClient := ssm.NewFromConfig(a.provider.config)
+
+	_, err := ssmClient.PutParameter(context.TODO(), &ssm.PutParameterInput{
+		Name:        aws.String(a.pathForPassphrase(app, stage)),
+		Type:        ssmTypes.ParameterTypeSecureString,
+		Value:       aws.String(passphrase),
+		Description: aws.String("DO NOT DELETE STATE WILL BECOME UNRECOVERABLE"),
+		Overwrite:   aws.Bool(true),
+	})
+	return err
+}
+
 func (a *AwsHome) Bootstrap() error {
 	_, err := a.provider.Bootstrap(a.provider.config.Region)
 	if err != nil {
@@ -3,12 +3,15 @@ package provider
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	_ "unsafe"
 
 	cloudflare "github.com/cloudflare/cloudflare-go"
@@ -17,6 +20,7 @@ import (
 
 type CloudflareProvider struct {
 	api              *cloudflare.API
+	authType         int
 	identifier       *cloudflare.ResourceContainer
 	defaultAccountId string
 }
@@ -45,9 +49,11 @@ func (c *CloudflareProvider) Init(app, stage string, args map[string]interface{}
 	var api *cloudflare.API
 	if apiToken != "" {
 		api, _ = cloudflare.NewWithAPIToken(apiToken)
+		c.authType = cloudflare.AuthToken
 	}
 	if apiKey != "" && email != "" {
 		api, _ = cloudflare.New(apiKey, email)
+		c.authType = cloudflare.AuthKeyEmail
 	}
 	if api == nil {
 		return util.NewReadableError(nil, "Cloudflare API not initialized. Please provide CLOUDFLARE_API_TOKEN or CLOUDFLARE_API_KEY and CLOUDFLARE_EMAIL environment variables or in the provider section of the project configuration file.")
@@ -134,6 +140,31 @@ func (c *CloudflareHome) putData(kind, app, stage string, data io.Reader) error
 	return nil
 }
 
+//go:linkname request github.com/cloudflare/cloudflare-go.(*API).request
+func request(*cloudflare.API, context.Context, string, string, io.Reader, int, http.Header) (*http.Response, error)
+
+// R2 rejects the write with 412 Precondition Failed if the object exists. The
+// client doesn't expose the status of a failed request so the raw response is
+// used.
+func (c *CloudflareHome) createData(kind, app, stage string, data io.Reader) error {
+	path := filepath.Join(kind, app, stage)
+	headers := http.Header{}
+	headers.Set("If-None-Match", "*")
+	resp, err := request(c.provider.api, context.Background(), http.MethodPut, "/accounts/"+c.provider.identifier.Identifier+"/r2/buckets/"+c.bootstrap.State+"/objects/"+path, data, c.provider.authType, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errDataExists
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to create %s: HTTP status %d: %s", path, resp.StatusCode, body)
+	}
+	return nil
+}
+
 func (c *CloudflareHome) getData(kind, app, stage string) (io.Reader, error) {
 	path := filepath.Join(kind, app, stage)
 	data, err := makeRequestContext(c.provider.api, context.Background(), http.MethodGet, "/accounts/"+c.provider.identifier.Identifier+"/r2/buckets/"+c.bootstrap.State+"/objects/"+path, nil)
@@ -155,11 +186,55 @@ func (c *CloudflareHome) removeData(kind, app, stage string) error {
 	return nil
 }
 
+type r2ListResponse struct {
+	Result []struct {
+		Key string `json:"key"`
+	} `json:"result"`
+	ResultInfo struct {
+		Cursor      string `json:"cursor"`
+		IsTruncated bool   `json:"is_truncated"`
+	} `json:"result_info"`
+}
+
+func (c *CloudflareHome) listData(kind, app, stage string) ([]string, error) {
+	prefix := filepath.Join(kind, app, stage) + "/"
+	result := []string{}
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("prefix", prefix)
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		data, err := makeRequestContext(c.provider.api, context.Background(), http.MethodGet, "/accounts/"+c.provider.identifier.Identifier+"/r2/buckets/"+c.bootstrap.State+"/objects?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var response r2ListResponse
+		err = json.Unmarshal(data, &response)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range response.Result {
+			result = append(result, strings.TrimPrefix(object.Key, prefix))
+		}
+		if !response.ResultInfo.IsTruncated || response.ResultInfo.Cursor == "" {
+			break
+		}
+		cursor = response.ResultInfo.Cursor
+	}
+	return result, nil
+}
+
 // these should go into secrets manager once it's out of beta
 func (c *CloudflareHome) setPassphrase(app, stage string, passphrase string) error {
 	return c.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
 }
 
+func (c *CloudflareHome) updatePassphrase(app, stage string, passphrase string) error {
+	return c.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
+}
+
 func (c *CloudflareHome) getPassphrase(app, stage string) (string, error) {
 	data, err := c.getData("passphrase", app, stage)
 	if err != nil {
@@ -0,0 +1,321 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/sst/ion/pkg/flag"
+	"github.com/sst/ion/pkg/global"
+	"golang.org/x/exp/slog"
+)
+
+// KeyProvider wraps the per-stage data key that secrets are sealed with. The
+// wrapped key is stored in the home next to the secrets, so reading them
+// requires access to whatever the provider wraps the key with.
+type KeyProvider interface {
+	Name() string
+	// newDataKey returns a fresh data key and the wrapped copy to store
+	newDataKey(app, stage string) (key []byte, wrapped []byte, err error)
+	unwrap(app, stage string, wrapped []byte) ([]byte, error)
+}
+
+// dataKey is what gets stored under the "datakey" key for stages that don't
+// seal their secrets with the passphrase
+type dataKey struct {
+	Provider string `json:"provider"`
+	KeyID    string `json:"keyID,omitempty"`
+	Wrapped  string `json:"wrapped"`
+}
+
+var ErrUnknownKeyProvider = fmt.Errorf("unknown key provider")
+
+// NewKeyProvider returns the key provider configured with SST_KEY_PROVIDER,
+// or nil when secrets should be sealed with the passphrase.
+func NewKeyProvider() (KeyProvider, error) {
+	switch flag.SST_KEY_PROVIDER {
+	case "", "passphrase":
+		return nil, nil
+	case "kms":
+		if flag.SST_KMS_KEY_ID == "" {
+			return nil, fmt.Errorf("the kms key provider requires SST_KMS_KEY_ID")
+		}
+		return &KmsKeyProvider{keyID: flag.SST_KMS_KEY_ID, endpoint: flag.SST_KMS_ENDPOINT}, nil
+	case "keyfile":
+		return &KeyfileProvider{path: keyfilePath()}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKeyProvider, flag.SST_KEY_PROVIDER)
+}
+
+func keyfilePath() string {
+	if flag.SST_KEYFILE != "" {
+		return flag.SST_KEYFILE
+	}
+	return filepath.Join(global.ConfigDir(), "keyfile")
+}
+
+func keyProviderFor(record *dataKey) (KeyProvider, error) {
+	switch record.Provider {
+	case "kms":
+		return &KmsKeyProvider{keyID: record.KeyID, endpoint: flag.SST_KMS_ENDPOINT}, nil
+	case "keyfile":
+		return &KeyfileProvider{path: keyfilePath()}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKeyProvider, record.Provider)
+}
+
+var secretKeyCache = map[Home]map[string]string{}
+
+// secretKey returns the key secrets are sealed with. Once a stage has a data
+// key it's always used, no matter what SST_KEY_PROVIDER is set to. Otherwise a
+// data key is created if a key provider is configured, and the passphrase is
+// used if not.
+func secretKey(backend Home, app, stage string) (string, error) {
+	cache, ok := secretKeyCache[backend]
+	if !ok {
+		cache = map[string]string{}
+		secretKeyCache[backend] = cache
+	}
+	if existing, ok := cache[app+stage]; ok {
+		return existing, nil
+	}
+
+	record, err := getDataKey(backend, app, stage)
+	if err != nil {
+		return "", err
+	}
+	if record == nil {
+		provider, err := NewKeyProvider()
+		if err != nil {
+			return "", err
+		}
+		if provider == nil {
+			return Passphrase(backend, app, stage)
+		}
+		record, err = createDataKey(backend, provider, app, stage)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	provider, err := keyProviderFor(record)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(record.Wrapped)
+	if err != nil {
+		return "", err
+	}
+	key, err := provider.unwrap(app, stage, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("could not unwrap data key with %s: %w", provider.Name(), err)
+	}
+	result := base64.StdEncoding.EncodeToString(key)
+	cache[app+stage] = result
+	return result, nil
+}
+
+func getDataKey(backend Home, app, stage string) (*dataKey, error) {
+	var record dataKey
+	err := getData(backend, "datakey", app, stage, false, &record)
+	if err != nil {
+		return nil, err
+	}
+	if record.Provider == "" {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// createDataKey stores a new data key for the stage and seals the existing
+// secrets with it
+func createDataKey(backend Home, provider KeyProvider, app, stage string) (*dataKey, error) {
+	slog.Info("creating data key", "provider", provider.Name(), "app", app, "stage", stage)
+	key, wrapped, err := provider.newDataKey(app, stage)
+	if err != nil {
+		return nil, err
+	}
+	record := &dataKey{
+		Provider: provider.Name(),
+		Wrapped:  base64.StdEncoding.EncodeToString(wrapped),
+	}
+	if kmsProvider, ok := provider.(*KmsKeyProvider); ok {
+		record.KeyID = kmsProvider.keyID
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	err = backend.createData("datakey", app, stage, bytes.NewReader(data))
+	if errors.Is(err, errDataExists) {
+		return getDataKey(backend, app, stage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := Passphrase(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	for _, sealedKey := range sealedKeys {
+		err := resealData(backend, sealedKey, app, stage, passphrase, base64.StdEncoding.EncodeToString(key))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// KmsKeyProvider wraps data keys with an AWS KMS key. The app and stage are
+// passed in as the encryption context so key policies can be scoped to them.
+// Set SST_KMS_ENDPOINT to point it at a different endpoint, like a local fake.
+type KmsKeyProvider struct {
+	keyID    string
+	endpoint string
+	client   *kms.Client
+}
+
+func (k *KmsKeyProvider) Name() string {
+	return "kms"
+}
+
+func (k *KmsKeyProvider) init() error {
+	if k.client != nil {
+		return nil
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), func(lo *config.LoadOptions) error {
+		// key arns are in the form arn:aws:kms:region:account:key/id
+		parts := strings.Split(k.keyID, ":")
+		if len(parts) > 3 && parts[0] == "arn" {
+			lo.Region = parts[3]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	k.client = kms.NewFromConfig(cfg, func(o *kms.Options) {
+		if k.endpoint != "" {
+			o.BaseEndpoint = aws.String(k.endpoint)
+		}
+	})
+	return nil
+}
+
+func (k *KmsKeyProvider) encryptionContext(app, stage string) map[string]string {
+	return map[string]string{
+		"sst:app":   app,
+		"sst:stage": stage,
+	}
+}
+
+func (k *KmsKeyProvider) newDataKey(app, stage string) ([]byte, []byte, error) {
+	err := k.init()
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := k.client.GenerateDataKey(context.Background(), &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(k.keyID),
+		KeySpec:           kmstypes.DataKeySpecAes256,
+		EncryptionContext: k.encryptionContext(app, stage),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Plaintext, result.CiphertextBlob, nil
+}
+
+func (k *KmsKeyProvider) unwrap(app, stage string, wrapped []byte) ([]byte, error) {
+	err := k.init()
+	if err != nil {
+		return nil, err
+	}
+	result, err := k.client.Decrypt(context.Background(), &kms.DecryptInput{
+		KeyId:             aws.String(k.keyID),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: k.encryptionContext(app, stage),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Plaintext, nil
+}
+
+// KeyfileProvider wraps data keys with a key stored in a file on this machine.
+// The file is created the first time it's needed. It's meant for the local
+// home, where the keyfile can be kept out of the state directory.
+type KeyfileProvider struct {
+	path string
+}
+
+func (k *KeyfileProvider) Name() string {
+	return "keyfile"
+}
+
+func (k *KeyfileProvider) key(create bool) (string, error) {
+	data, err := os.ReadFile(k.path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return "", err
+	}
+	slog.Info("creating keyfile", "path", k.path)
+	random := make([]byte, 32)
+	_, err = rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	key := base64.StdEncoding.EncodeToString(random)
+	err = os.MkdirAll(filepath.Dir(k.path), 0700)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.OpenFile(k.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return k.key(false)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	_, err = file.WriteString(key + "\n")
+	return key, err
+}
+
+func (k *KeyfileProvider) newDataKey(app, stage string) ([]byte, []byte, error) {
+	keyfile, err := k.key(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := seal(keyfile, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, wrapped, nil
+}
+
+func (k *KeyfileProvider) unwrap(app, stage string, wrapped []byte) ([]byte, error) {
+	keyfile, err := k.key(false)
+	if err != nil {
+		return nil, err
+	}
+	return unseal(keyfile, wrapped)
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeKms wraps keys by prefixing them, which is enough to check the requests
+// the provider makes
+func fakeKms(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			KeyId             string
+			CiphertextBlob    []byte
+			EncryptionContext map[string]string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+			w.WriteHeader(400)
+			return
+		}
+		if body.EncryptionContext["sst:stage"] != "production" {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+			return
+		}
+		key := bytes.Repeat([]byte{7}, 32)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch {
+		case strings.HasSuffix(r.Header.Get("X-Amz-Target"), "GenerateDataKey"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"KeyId":          body.KeyId,
+				"Plaintext":      key,
+				"CiphertextBlob": append([]byte("wrapped:"), key...),
+			})
+		case strings.HasSuffix(r.Header.Get("X-Amz-Target"), "Decrypt"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"KeyId":     body.KeyId,
+				"Plaintext": bytes.TrimPrefix(body.CiphertextBlob, []byte("wrapped:")),
+			})
+		}
+	}))
+}
+
+func TestKeyProviders(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	server := fakeKms(t)
+	defer server.Close()
+
+	providers := []KeyProvider{
+		&KmsKeyProvider{keyID: "alias/sst", endpoint: server.URL},
+		&KeyfileProvider{path: filepath.Join(t.TempDir(), "keyfile")},
+	}
+	for _, provider := range providers {
+		t.Run(provider.Name(), func(t *testing.T) {
+			key, wrapped, err := provider.newDataKey("app", "production")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(key, wrapped) {
+				t.Fatal("data key was stored unwrapped")
+			}
+			unwrapped, err := provider.unwrap("app", "production", wrapped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(key, unwrapped) {
+				t.Fatal("unwrapped key does not match")
+			}
+		})
+	}
+
+	_, wrapped, err := providers[0].newDataKey("app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = providers[0].unwrap("app", "dev", wrapped)
+	if err == nil {
+		t.Fatal("expected unwrap with a different stage to fail")
+	}
+}
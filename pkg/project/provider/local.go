@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type LocalHome struct {
@@ -53,16 +54,60 @@ func (l *LocalHome) putData(key, app, stage string, data io.Reader) error {
 	return nil
 }
 
+func (l *LocalHome) createData(key, app, stage string, data io.Reader) error {
+	p := l.pathForData(key, app, stage)
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return errDataExists
+		}
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (l *LocalHome) removeData(key, app, stage string) error {
 	p := l.pathForData(key, app, stage)
 	return os.Remove(p)
 }
 
+func (l *LocalHome) listData(key, app, stage string) ([]string, error) {
+	dir := filepath.Join(global.ConfigDir(), "state", key, app, stage)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	result := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		result = append(result, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return result, nil
+}
+
 // these should go into secrets manager once it's out of beta
 func (c *LocalHome) setPassphrase(app, stage string, passphrase string) error {
 	return c.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
 }
 
+func (c *LocalHome) updatePassphrase(app, stage string, passphrase string) error {
+	return c.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
+}
+
 func (c *LocalHome) getPassphrase(app, stage string) (string, error) {
 	data, err := c.getData("passphrase", app, stage)
 	if err != nil {
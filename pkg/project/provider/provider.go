@@ -10,9 +10,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/user"
+	"sort"
 	"time"
 
 	"github.com/sst/ion/pkg/flag"
+	"github.com/sst/ion/pkg/id"
 	"golang.org/x/exp/slog"
 	"golang.org/x/sync/errgroup"
 )
@@ -21,11 +24,26 @@ type Home interface {
 	Bootstrap() error
 	getData(key, app, stage string) (io.Reader, error)
 	putData(key, app, stage string, data io.Reader) error
+	// createData writes data only if nothing is stored under the key yet,
+	// otherwise it returns errDataExists without writing anything.
+	createData(key, app, stage string, data io.Reader) error
 	removeData(key, app, stage string) error
+	listData(key, app, stage string) ([]string, error)
 	setPassphrase(app, stage string, passphrase string) error
+	// updatePassphrase replaces an existing passphrase
+	updatePassphrase(app, stage string, passphrase string) error
 	getPassphrase(app, stage string) (string, error)
 }
 
+// versionedHome is implemented by the homes that can make a write conditional
+// on the version of the data that was read
+type versionedHome interface {
+	getDataVersion(key, app, stage string) (io.Reader, string, error)
+	// replaceData writes data only if the stored version still matches,
+	// otherwise it returns errDataChanged without writing anything.
+	replaceData(key, app, stage, version string, data io.Reader) error
+}
+
 type DevTransport struct {
 	In  chan string
 	Out chan string
@@ -60,21 +78,144 @@ var ErrLockExists = fmt.Errorf("Concurrent update detected, run `sst unlock --st
 
 var passphraseCache = map[Home]map[string]string{}
 
-func Copy(from Home, to Home, app, stage string) error {
-	reader, err := from.getData("app", app, stage)
+// Copy moves the full state of a stage from one home to another. The secrets
+// and the secret values in the state and snapshots are decrypted with the
+// passphrase of the source and encrypted again with the passphrase of the
+// destination, everything else is copied as is. The fallback secrets of the
+// app are copied too, but the ones the destination already has are left alone.
+// The names of the ones that were skipped because their value differs are
+// returned.
+func Copy(from Home, to Home, app, stage string) ([]string, error) {
+	slog.Info("copying state", "app", app, "stage", stage)
+	source, err := Passphrase(from, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := Passphrase(to, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	states, err := newStateCrypter(source, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	err = copyState(from, to, states, "app", app, stage)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := GetSecrets(from, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) > 0 {
+		err = putData(to, "secret", app, stage, true, secrets)
+		if err != nil {
+			return nil, err
+		}
+	}
+	history, err := GetSecretHistory(from, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 {
+		err = putSecretHistory(to, app, stage, history)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	skipped, err := copyFallbackSecrets(from, to, app)
+	if err != nil {
+		return nil, err
+	}
+
+	var group errgroup.Group
+	group.SetLimit(10)
+	for _, key := range []string{"snapshot", "summary", "update"} {
+		ids, err := from.listData(key, app, stage)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			key, id := key, id
+			group.Go(func() error {
+				if key == "snapshot" {
+					return copyState(from, to, states, key, app, stage+"/"+id)
+				}
+				return copyData(from, to, key, app, stage+"/"+id)
+			})
+		}
+	}
+	return skipped, group.Wait()
+}
+
+// copyFallbackSecrets adds the fallback secrets that the destination doesn't
+// have yet, since other stages of the app may already be using it
+func copyFallbackSecrets(from Home, to Home, app string) ([]string, error) {
+	source, err := GetSecrets(from, app, "")
+	if err != nil || len(source) == 0 {
+		return nil, err
+	}
+	destination, err := GetSecrets(to, app, "")
+	if err != nil {
+		return nil, err
+	}
+	skipped := []string{}
+	added := false
+	for key, value := range source {
+		if existing, ok := destination[key]; ok {
+			if existing != value {
+				skipped = append(skipped, key)
+			}
+			continue
+		}
+		destination[key] = value
+		added = true
+	}
+	sort.Strings(skipped)
+	if !added {
+		return skipped, nil
+	}
+	return skipped, putData(to, "secret", app, "_fallback", true, destination)
+}
+
+// copyState copies a pulumi checkpoint, encrypting its secrets with the
+// passphrase of the destination
+func copyState(from Home, to Home, crypter *stateCrypter, key, app, stage string) error {
+	reader, err := from.getData(key, app, stage)
 	if err != nil {
 		return err
 	}
-	err = to.putData("app", app, stage, reader)
+	if reader == nil {
+		return nil
+	}
+	data, err := io.ReadAll(reader)
 	if err != nil {
 		return err
 	}
-	reader, err = from.getData("secret", app, stage)
+	if crypter.from != crypter.to {
+		rotated, changed, err := crypter.rotate(data)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", key, stage, err)
+		}
+		if changed {
+			data = rotated
+		}
+	}
+	return to.putData(key, app, stage, bytes.NewReader(data))
+}
+
+func copyData(from Home, to Home, key, app, stage string) error {
+	reader, err := from.getData(key, app, stage)
 	if err != nil {
 		return err
 	}
-	to.putData("secret", app, stage, reader)
-	return nil
+	if reader == nil {
+		return nil
+	}
+	return to.putData(key, app, stage, reader)
 }
 
 func Passphrase(backend Home, app, stage string) (string, error) {
@@ -156,6 +297,198 @@ func PutUpdate(backend Home, app, stage string, update Update) error {
 	return putData(backend, "update", app, stage+"/"+update.ID, false, update)
 }
 
+// ListUpdates returns the updates of a stage, newest first.
+func ListUpdates(backend Home, app, stage string) ([]string, error) {
+	slog.Info("listing updates", "app", app, "stage", stage)
+	ids, err := backend.listData("update", app, stage)
+	if err != nil {
+		return nil, err
+	}
+	// update ids are descending so the newest sorts first
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func GetUpdate(backend Home, app, stage, updateID string) (*Update, error) {
+	var update Update
+	err := getData(backend, "update", app, stage+"/"+updateID, false, &update)
+	if err != nil {
+		return nil, err
+	}
+	if update.ID == "" {
+		return nil, nil
+	}
+	return &update, nil
+}
+
+func GetSummary(backend Home, app, stage, updateID string) (*Summary, error) {
+	var summary Summary
+	err := getData(backend, "summary", app, stage+"/"+updateID, false, &summary)
+	if err != nil {
+		return nil, err
+	}
+	if summary.UpdateID == "" {
+		return nil, nil
+	}
+	return &summary, nil
+}
+
+var ErrSnapshotNotFound = fmt.Errorf("snapshot not found")
+
+// PullSnapshot writes the state as it was after the given update to out.
+func PullSnapshot(backend Home, app, stage, updateID string, out io.Writer) error {
+	slog.Info("pulling snapshot", "app", app, "stage", stage, "updateID", updateID)
+	reader, err := backend.getData("snapshot", app, stage+"/"+updateID)
+	if err != nil {
+		return err
+	}
+	if reader == nil {
+		return ErrSnapshotNotFound
+	}
+	_, err = io.Copy(out, reader)
+	return err
+}
+
+// PutRollback records a rollback as an update along with its summary. A failed
+// rollback is recorded with the error so it doesn't look like it worked.
+func PutRollback(backend Home, app, stage string, summary Summary, failure error) error {
+	if failure != nil {
+		summary.Errors = append(summary.Errors, SummaryError{
+			Message: failure.Error(),
+		})
+	}
+	summary.TimeCompleted = time.Now().UTC().Format(time.RFC3339)
+	err := PutSummary(backend, app, stage, summary.UpdateID, summary)
+	if err != nil {
+		return err
+	}
+	return PutUpdate(backend, app, stage, Update{
+		ID:            summary.UpdateID,
+		Version:       summary.Version,
+		Command:       summary.Command,
+		Errors:        summary.Errors,
+		TimeStarted:   summary.TimeStarted,
+		TimeCompleted: summary.TimeCompleted,
+	})
+}
+
+// RetentionPolicy decides which updates are kept when pruning. An update is
+// kept if it's one of the last Keep updates or if it's newer than MaxAge.
+type RetentionPolicy struct {
+	Keep   int
+	MaxAge time.Duration
+}
+
+// Expired returns the ids, sorted newest first, that fall outside the policy.
+// The newest update is always kept.
+func (r RetentionPolicy) Expired(ids []string, now time.Time) []string {
+	result := []string{}
+	for index, updateID := range ids {
+		if index == 0 || index < r.Keep {
+			continue
+		}
+		if r.MaxAge > 0 {
+			created, err := id.DescendingTime(updateID)
+			if err != nil || now.Sub(created) < r.MaxAge {
+				continue
+			}
+		}
+		result = append(result, updateID)
+	}
+	return result
+}
+
+// Prune removes the snapshots, summaries and updates that fall outside the
+// retention policy and returns the ids that were removed. The policy counts
+// the updates that have a snapshot, so the records written by commands like
+// gc itself don't push real updates out. The other records are removed once
+// they're older than the oldest snapshot that's kept.
+func Prune(backend Home, app, stage string, policy RetentionPolicy, dryRun bool) ([]string, error) {
+	slog.Info("pruning state", "app", app, "stage", stage, "keep", policy.Keep, "maxAge", policy.MaxAge)
+	keys := []string{"snapshot", "summary", "update", "takeover"}
+	stored := map[string][]string{}
+	all := map[string]bool{}
+	for _, key := range keys {
+		ids, err := backend.listData(key, app, stage)
+		if err != nil {
+			return nil, err
+		}
+		stored[key] = ids
+		for _, updateID := range ids {
+			all[updateID] = true
+		}
+	}
+	var lock lockData
+	err := getData(backend, "lock", app, stage, false, &lock)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	snapshots := append([]string{}, stored["snapshot"]...)
+	sort.Strings(snapshots)
+	expiredSnapshots := map[string]bool{}
+	for _, updateID := range policy.Expired(snapshots, now) {
+		expiredSnapshots[updateID] = true
+	}
+	oldestKept := ""
+	for _, updateID := range snapshots {
+		if !expiredSnapshots[updateID] {
+			oldestKept = updateID
+		}
+	}
+	hasSnapshot := map[string]bool{}
+	for _, updateID := range snapshots {
+		hasSnapshot[updateID] = true
+	}
+	expired := []string{}
+	for updateID := range all {
+		if updateID == lock.UpdateID {
+			continue
+		}
+		if hasSnapshot[updateID] {
+			if expiredSnapshots[updateID] {
+				expired = append(expired, updateID)
+			}
+			continue
+		}
+		// ids sort newest first, so a larger id is an older update
+		if oldestKept == "" || updateID <= oldestKept {
+			continue
+		}
+		if policy.MaxAge > 0 {
+			created, err := id.DescendingTime(updateID)
+			if err != nil || now.Sub(created) < policy.MaxAge {
+				continue
+			}
+		}
+		expired = append(expired, updateID)
+	}
+	sort.Strings(expired)
+	if dryRun || len(expired) == 0 {
+		return expired, nil
+	}
+
+	remove := map[string]bool{}
+	for _, id := range expired {
+		remove[id] = true
+	}
+	var group errgroup.Group
+	group.SetLimit(10)
+	for _, key := range keys {
+		for _, id := range stored[key] {
+			if !remove[id] {
+				continue
+			}
+			key, id := key, id
+			group.Go(func() error {
+				return backend.removeData(key, app, stage+"/"+id)
+			})
+		}
+	}
+	return expired, group.Wait()
+}
+
 func GetSecrets(backend Home, app, stage string) (map[string]string, error) {
 	if stage == "" {
 		stage = "_fallback"
@@ -168,14 +501,28 @@ func GetSecrets(backend Home, app, stage string) (map[string]string, error) {
 	return data, err
 }
 
-func PutSecrets(backend Home, app, stage string, data map[string]string) error {
+// PutSecrets replaces the secrets of a stage and records the ones that changed
+// in the secret history
+func PutSecrets(backend Home, version, app, stage string, data map[string]string) error {
+	return putSecrets(backend, version, app, stage, data, 0)
+}
+
+func putSecrets(backend Home, version, app, stage string, data map[string]string, restored int) error {
+	if data == nil {
+		return nil
+	}
+	current, err := GetSecrets(backend, app, stage)
+	if err != nil {
+		return err
+	}
+	err = recordSecrets(backend, version, app, stage, current, data, restored)
+	if err != nil {
+		return err
+	}
 	if stage == "" {
 		stage = "_fallback"
 	}
 	slog.Info("putting secrets", "app", app, "stage", stage)
-	if data == nil {
-		return nil
-	}
 	return putData(backend, "secret", app, stage, true, data)
 }
 
@@ -227,29 +574,88 @@ func PullState(backend Home, app, stage string, out string) error {
 }
 
 type lockData struct {
-	Created  time.Time `json:"created"`
-	UpdateID string    `json:"updateID"`
-	RunID    string    `json:"runID"`
-	Command  string    `json:"command"`
-	Ignore   bool      `json:"ignore"`
+	Created   time.Time `json:"created"`
+	Heartbeat time.Time `json:"heartbeat"`
+	UpdateID  string    `json:"updateID"`
+	RunID     string    `json:"runID"`
+	Command   string    `json:"command"`
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+	Ignore    bool      `json:"ignore"`
 }
 
-func Lock(backend Home, updateID, version, command, app, stage string) error {
-	slog.Info("locking", "app", app, "stage", stage)
-	var lockData lockData
-	err := getData(backend, "lock", app, stage, false, &lockData)
+// LockExistsError is returned when the stage is locked by someone else. It
+// matches ErrLockExists with errors.Is.
+type LockExistsError struct {
+	Created  time.Time
+	UpdateID string
+	Command  string
+	User     string
+	Host     string
+}
+
+func (e *LockExistsError) Error() string {
+	if e.User == "" {
+		return ErrLockExists.Error()
+	}
+	return fmt.Sprintf("%s Locked by %s@%s running `sst %s` since %s.", ErrLockExists.Error(), e.User, e.Host, e.Command, e.Created.Local().Format(time.RFC1123))
+}
+
+func (e *LockExistsError) Is(target error) bool {
+	return target == ErrLockExists
+}
+
+var errDataExists = fmt.Errorf("data already exists")
+var errDataChanged = fmt.Errorf("data changed since it was read")
+
+var ErrLockLost = fmt.Errorf("lock was released or taken over by another update")
+
+// locks older than this without a heartbeat are considered stale and can be
+// taken over. set SST_LOCK_TTL=0 to disable.
+var LockTTL = func() time.Duration {
+	if flag.SST_LOCK_TTL == "" {
+		return 10 * time.Minute
+	}
+	ttl, err := time.ParseDuration(flag.SST_LOCK_TTL)
 	if err != nil {
-		return err
+		slog.Warn("invalid SST_LOCK_TTL, using default", "value", flag.SST_LOCK_TTL)
+		return 10 * time.Minute
 	}
-	if !lockData.Created.IsZero() {
-		return ErrLockExists
+	return ttl
+}()
+
+func (l *lockData) stale() bool {
+	// locks written by older versions never heartbeat so they can't expire
+	if LockTTL <= 0 || l.Heartbeat.IsZero() {
+		return false
 	}
+	return time.Since(l.Heartbeat) > LockTTL
+}
+
+func Lock(backend Home, updateID, version, command, app, stage string) error {
+	slog.Info("locking", "app", app, "stage", stage)
+	var lockData lockData
 	lockData.RunID = os.Getenv("SST_RUN_ID")
 	lockData.Created = time.Now()
+	lockData.Heartbeat = lockData.Created
 	lockData.UpdateID = updateID
 	lockData.Command = command
 	lockData.Ignore = true
-	err = putData(backend, "lock", app, stage, false, lockData)
+	if current, err := user.Current(); err == nil {
+		lockData.User = current.Username
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		lockData.Host = hostname
+	}
+	lockBytes, err := json.Marshal(lockData)
+	if err != nil {
+		return err
+	}
+
+	err = backend.createData("lock", app, stage, bytes.NewReader(lockBytes))
+	if err == errDataExists {
+		err = takeoverLock(backend, app, stage, lockBytes)
+	}
 	if err != nil {
 		return err
 	}
@@ -268,6 +674,150 @@ func Lock(backend Home, updateID, version, command, app, stage string) error {
 	return nil
 }
 
+// takeoverLock replaces an existing lock if it's stale. Only one update can
+// take over a given stale lock since the takeover marker is created atomically.
+func takeoverLock(backend Home, app, stage string, lockBytes []byte) error {
+	var existing lockData
+	err := getData(backend, "lock", app, stage, false, &existing)
+	if err != nil {
+		return err
+	}
+	if existing.Created.IsZero() {
+		// the lock was released in the meantime, try to grab it once more
+		err = backend.createData("lock", app, stage, bytes.NewReader(lockBytes))
+		if err != errDataExists {
+			return err
+		}
+		err = getData(backend, "lock", app, stage, false, &existing)
+		if err != nil {
+			return err
+		}
+		return newLockExistsError(existing)
+	}
+	lockErr := newLockExistsError(existing)
+	if !existing.stale() {
+		return lockErr
+	}
+	slog.Warn("taking over stale lock", "updateID", existing.UpdateID, "heartbeat", existing.Heartbeat, "user", existing.User, "host", existing.Host)
+	marker := stage + "/" + existing.UpdateID
+	err = backend.createData("takeover", app, marker, bytes.NewReader(lockBytes))
+	if err == errDataExists {
+		return lockErr
+	}
+	if err != nil {
+		return err
+	}
+	// the marker is removed once the lock is replaced, so make sure another
+	// update didn't take over the same stale lock before that
+	var current lockData
+	err = getData(backend, "lock", app, stage, false, &current)
+	if err == nil && current.UpdateID != existing.UpdateID {
+		err = newLockExistsError(current)
+	}
+	if err == nil {
+		err = backend.putData("lock", app, stage, bytes.NewReader(lockBytes))
+	}
+	removeErr := backend.removeData("takeover", app, marker)
+	if removeErr != nil {
+		slog.Warn("failed to remove takeover marker", "err", removeErr)
+	}
+	return err
+}
+
+func newLockExistsError(existing lockData) *LockExistsError {
+	return &LockExistsError{
+		Created:  existing.Created,
+		UpdateID: existing.UpdateID,
+		Command:  existing.Command,
+		User:     existing.User,
+		Host:     existing.Host,
+	}
+}
+
+// RenewLock extends the lease on a lock held by updateID. On the homes that
+// support it the lock is only written if it hasn't changed since it was read,
+// so a renewal can't overwrite a lock that was taken over in between.
+func RenewLock(backend Home, updateID, app, stage string) error {
+	versioned, ok := backend.(versionedHome)
+	if !ok {
+		var lockData lockData
+		err := getData(backend, "lock", app, stage, false, &lockData)
+		if err != nil {
+			return err
+		}
+		if lockData.UpdateID != updateID {
+			return ErrLockLost
+		}
+		lockData.Heartbeat = time.Now()
+		return putData(backend, "lock", app, stage, false, lockData)
+	}
+	reader, version, err := versioned.getDataVersion("lock", app, stage)
+	if err != nil {
+		return err
+	}
+	if reader == nil {
+		return ErrLockLost
+	}
+	var lockData lockData
+	err = json.NewDecoder(reader).Decode(&lockData)
+	if err != nil {
+		return err
+	}
+	if lockData.UpdateID != updateID {
+		return ErrLockLost
+	}
+	lockData.Heartbeat = time.Now()
+	lockBytes, err := json.Marshal(lockData)
+	if err != nil {
+		return err
+	}
+	err = versioned.replaceData("lock", app, stage, version, bytes.NewReader(lockBytes))
+	if err == errDataChanged {
+		return ErrLockLost
+	}
+	return err
+}
+
+// Heartbeat renews the lock in the background until the returned function is
+// called. The returned function blocks until the last renewal is done so the
+// lock can be safely removed afterwards. If the lock is lost, lost is called
+// with ErrLockLost and the heartbeat stops.
+func Heartbeat(backend Home, updateID, app, stage string, lost func(error)) func() {
+	if LockTTL <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(LockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				slog.Info("renewing lock", "app", app, "stage", stage)
+				err := RenewLock(backend, updateID, app, stage)
+				if err == ErrLockLost {
+					slog.Warn("lock lost", "app", app, "stage", stage)
+					if lost != nil {
+						lost(err)
+					}
+					return
+				}
+				if err != nil {
+					slog.Error("failed to renew lock", "err", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
 func Unlock(backend Home, app, stage string) error {
 	slog.Info("unlocking", "app", app, "stage", stage)
 	return removeData(backend, "lock", app, stage)
@@ -280,27 +830,14 @@ func putData(backend Home, key, app, stage string, encrypt bool, data interface{
 		return err
 	}
 	if encrypt {
-		passphrase, err := Passphrase(backend, app, stage)
+		key, err := secretKey(backend, app, stage)
 		if err != nil {
 			return err
 		}
-		passphraseBytes, err := base64.StdEncoding.DecodeString(passphrase)
+		jsonBytes, err = seal(key, jsonBytes)
 		if err != nil {
 			return err
 		}
-		blockCipher, err := aes.NewCipher(passphraseBytes)
-		if err != nil {
-			return err
-		}
-		gcm, err := cipher.NewGCM(blockCipher)
-		if err != nil {
-			return err
-		}
-		nonce := make([]byte, gcm.NonceSize())
-		if _, err = rand.Read(nonce); err != nil {
-			return err
-		}
-		jsonBytes = gcm.Seal(nonce, nonce, jsonBytes, nil)
 	}
 	return backend.putData(key, app, stage, bytes.NewReader(jsonBytes))
 }
@@ -321,32 +858,92 @@ func getData(backend Home, key, app, stage string, encrypted bool, out interface
 	}
 
 	if encrypted {
-		passphrase, err := Passphrase(backend, app, stage)
-		if err != nil {
-			return err
-		}
-		passphraseBytes, err := base64.StdEncoding.DecodeString(passphrase)
-		if err != nil {
-			return err
-		}
-		blockCipher, err := aes.NewCipher(passphraseBytes)
+		key, err := secretKey(backend, app, stage)
 		if err != nil {
 			return err
 		}
-		gcm, err := cipher.NewGCM(blockCipher)
+		sealed := data
+		data, err = unseal(key, sealed)
 		if err != nil {
-			return err
+			// data sealed with the passphrase before the stage got a data key
+			passphrase, passphraseErr := Passphrase(backend, app, stage)
+			if passphraseErr != nil || passphrase == key {
+				return err
+			}
+			data, err = unseal(passphrase, sealed)
+			if err != nil {
+				return err
+			}
 		}
+	}
 
-		nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return json.Unmarshal(data, out)
+}
 
-		data, err = gcm.Open(nil, nonce, ciphertext, nil)
-		if err != nil {
-			return err
+// seal encrypts data with AES-GCM using the passphrase as the key
+func seal(passphrase string, data []byte) ([]byte, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+func unseal(passphrase string, data []byte) ([]byte, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// sealedKeys are the keys that are stored sealed with the secret key
+var sealedKeys = []string{"secret", "secrethistory"}
+
+// resealData seals the data under a key again with a different key. Data that
+// is already sealed with the new key is left alone, so an interrupted reseal
+// can be run again.
+func resealData(backend Home, key, app, stage, from, to string) error {
+	reader, err := backend.getData(key, app, stage)
+	if err != nil || reader == nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	plaintext, err := unseal(from, data)
+	if err != nil {
+		if _, toErr := unseal(to, data); toErr == nil {
+			return nil
 		}
+		return err
+	}
+	sealed, err := seal(to, plaintext)
+	if err != nil {
+		return err
 	}
+	return backend.putData(key, app, stage, bytes.NewReader(sealed))
+}
 
-	return json.Unmarshal(data, out)
+func newGCM(passphrase string) (cipher.AEAD, error) {
+	passphraseBytes, err := base64.StdEncoding.DecodeString(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	blockCipher, err := aes.NewCipher(passphraseBytes)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(blockCipher)
 }
 
 func removeData(backend Home, key, app, stage string) error {
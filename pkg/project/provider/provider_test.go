@@ -0,0 +1,544 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/sig"
+)
+
+// memoryHome keeps everything in memory, which is enough to test the logic
+// shared by all the homes
+type memoryHome struct {
+	mutex sync.Mutex
+	data  map[string][]byte
+}
+
+func newMemoryHome() *memoryHome {
+	return &memoryHome{data: map[string][]byte{}}
+}
+
+func (m *memoryHome) Bootstrap() error { return nil }
+
+func (m *memoryHome) getData(key, app, stage string) (io.Reader, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	data, ok := m.data[key+"/"+app+"/"+stage]
+	if !ok {
+		return nil, nil
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (m *memoryHome) putData(key, app, stage string, data io.Reader) error {
+	read, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.data[key+"/"+app+"/"+stage] = read
+	return nil
+}
+
+func (m *memoryHome) createData(key, app, stage string, data io.Reader) error {
+	m.mutex.Lock()
+	_, ok := m.data[key+"/"+app+"/"+stage]
+	m.mutex.Unlock()
+	if ok {
+		return errDataExists
+	}
+	return m.putData(key, app, stage, data)
+}
+
+func (m *memoryHome) removeData(key, app, stage string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.data, key+"/"+app+"/"+stage)
+	return nil
+}
+
+func (m *memoryHome) listData(key, app, stage string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	prefix := key + "/" + app + "/" + stage + "/"
+	result := []string{}
+	for path := range m.data {
+		if strings.HasPrefix(path, prefix) {
+			result = append(result, strings.TrimPrefix(path, prefix))
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryHome) setPassphrase(app, stage string, passphrase string) error {
+	return m.putData("passphrase", app, stage, strings.NewReader(passphrase))
+}
+
+func (m *memoryHome) updatePassphrase(app, stage string, passphrase string) error {
+	return m.setPassphrase(app, stage, passphrase)
+}
+
+func (m *memoryHome) getPassphrase(app, stage string) (string, error) {
+	reader, _ := m.getData("passphrase", app, stage)
+	if reader == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(reader)
+	return string(data), err
+}
+
+func randomPassphrase(t *testing.T) string {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(random)
+}
+
+// checkpoint returns a pulumi checkpoint with one secret output encrypted
+// with the passphrase
+func checkpoint(t *testing.T, passphrase string, secret string) []byte {
+	ctx := context.Background()
+	salt := []byte("saltsalt")
+	crypter := config.NewSymmetricCrypterFromPassphrase(passphrase, salt)
+	check, err := crypter.EncryptValue(ctx, "pulumi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := crypter.EncryptValue(ctx, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"checkpoint": map[string]interface{}{
+			"latest": map[string]interface{}{
+				"secrets_providers": map[string]interface{}{
+					"type":  "passphrase",
+					"state": map[string]interface{}{"salt": "v1:" + base64.StdEncoding.EncodeToString(salt) + ":" + check},
+				},
+				"resources": []interface{}{
+					map[string]interface{}{
+						"outputs": map[string]interface{}{
+							"password": map[string]interface{}{sig.Key: sig.Secret, "ciphertext": ciphertext},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// decryptCheckpoint returns the secret output in a checkpoint
+func decryptCheckpoint(t *testing.T, passphrase string, data []byte) string {
+	var parsed struct {
+		Checkpoint struct {
+			Latest struct {
+				SecretsProviders struct {
+					State struct {
+						Salt string `json:"salt"`
+					} `json:"state"`
+				} `json:"secrets_providers"`
+				Resources []struct {
+					Outputs map[string]map[string]string `json:"outputs"`
+				} `json:"resources"`
+			} `json:"latest"`
+		} `json:"checkpoint"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(parsed.Checkpoint.Latest.SecretsProviders.State.Salt, ":", 3)
+	salt, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypter := config.NewSymmetricCrypterFromPassphrase(passphrase, salt)
+	plaintext, err := crypter.DecryptValue(context.Background(), parsed.Checkpoint.Latest.Resources[0].Outputs["password"]["ciphertext"])
+	if err != nil {
+		t.Fatalf("could not decrypt with the passphrase: %v", err)
+	}
+	return plaintext
+}
+
+func TestCopy(t *testing.T) {
+	from := newMemoryHome()
+	to := newMemoryHome()
+	source := randomPassphrase(t)
+	destination := randomPassphrase(t)
+	from.setPassphrase("app", "production", source)
+	to.setPassphrase("app", "production", destination)
+
+	from.putData("app", "app", "production", bytes.NewReader(checkpoint(t, source, "hunter2")))
+	from.putData("snapshot", "app", "production/01", bytes.NewReader(checkpoint(t, source, "hunter1")))
+	if err := putData(from, "secret", "app", "production", true, map[string]string{"Token": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := putData(from, "secret", "app", "_fallback", true, map[string]string{"Shared": "from", "Region": "us-east-1", "Same": "same"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := putData(to, "secret", "app", "_fallback", true, map[string]string{"Shared": "to", "Same": "same"}); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped, err := Copy(from, to, "app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if passphrase, _ := to.getPassphrase("app", "production"); passphrase != destination {
+		t.Fatal("expected the destination passphrase to be kept")
+	}
+	secrets, err := GetSecrets(to, "app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets["Token"] != "abc" {
+		t.Fatalf("unexpected secrets %v", secrets)
+	}
+	fallback, err := GetSecrets(to, "app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fallback["Region"] != "us-east-1" || fallback["Shared"] != "to" || fallback["Same"] != "same" {
+		t.Fatalf("expected the missing fallback secrets to be added without overwriting, got %v", fallback)
+	}
+	if !slices.Equal(skipped, []string{"Shared"}) {
+		t.Fatalf("expected the fallback secret with a different value to be skipped, got %v", skipped)
+	}
+	state, _ := to.getData("app", "app", "production")
+	data, _ := io.ReadAll(state)
+	if decryptCheckpoint(t, destination, data) != "hunter2" {
+		t.Fatal("expected the state to be re-encrypted")
+	}
+	snapshot, _ := to.getData("snapshot", "app", "production/01")
+	data, _ = io.ReadAll(snapshot)
+	if decryptCheckpoint(t, destination, data) != "hunter1" {
+		t.Fatal("expected the snapshot to be re-encrypted")
+	}
+}
+
+// versionedMemoryHome uses the content of the data as its version, and runs
+// beforeReplace between reading and writing to simulate a concurrent writer
+type versionedMemoryHome struct {
+	*memoryHome
+	beforeReplace func()
+}
+
+func (m *versionedMemoryHome) getDataVersion(key, app, stage string) (io.Reader, string, error) {
+	reader, err := m.getData(key, app, stage)
+	if err != nil || reader == nil {
+		return nil, "", err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(data), string(data), nil
+}
+
+func (m *versionedMemoryHome) replaceData(key, app, stage, version string, data io.Reader) error {
+	if m.beforeReplace != nil {
+		m.beforeReplace()
+	}
+	m.mutex.Lock()
+	current, ok := m.data[key+"/"+app+"/"+stage]
+	m.mutex.Unlock()
+	if !ok || string(current) != version {
+		return errDataChanged
+	}
+	return m.putData(key, app, stage, data)
+}
+
+func TestRenewLock(t *testing.T) {
+	backend := &versionedMemoryHome{memoryHome: newMemoryHome()}
+	err := Lock(backend, "first", "dev", "deploy", "app", "stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RenewLock(backend, "first", "app", "stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another update takes the lock over after it was read for the renewal
+	backend.beforeReplace = func() {
+		backend.beforeReplace = nil
+		err := putData(backend, "lock", "app", "stage", false, lockData{UpdateID: "second"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = RenewLock(backend, "first", "app", "stage")
+	if err != ErrLockLost {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+	var current lockData
+	err = getData(backend, "lock", "app", "stage", false, &current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.UpdateID != "second" {
+		t.Fatalf("renewal overwrote the lock of %s", current.UpdateID)
+	}
+}
+
+func TestHeartbeatLost(t *testing.T) {
+	ttl := LockTTL
+	LockTTL = 30 * time.Millisecond
+	defer func() { LockTTL = ttl }()
+
+	backend := newMemoryHome()
+	err := Lock(backend, "first", "dev", "deploy", "app", "stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lost := make(chan error, 1)
+	stop := Heartbeat(backend, "first", "app", "stage", func(err error) {
+		lost <- err
+	})
+	defer stop()
+	err = Unlock(backend, "app", "stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-lost:
+		if err != ErrLockLost {
+			t.Fatalf("expected ErrLockLost, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat did not report the lost lock")
+	}
+}
+
+func TestTakeoverLock(t *testing.T) {
+	backend := newMemoryHome()
+	stale := lockData{
+		Created:   time.Now().Add(-2 * LockTTL),
+		Heartbeat: time.Now().Add(-2 * LockTTL),
+		UpdateID:  "stale",
+		User:      "someone",
+	}
+	err := putData(backend, "lock", "app", "stage", false, stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Lock(backend, "first", "dev", "deploy", "app", "stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var current lockData
+	err = getData(backend, "lock", "app", "stage", false, &current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.UpdateID != "first" {
+		t.Fatalf("expected the lock to be taken over, got %s", current.UpdateID)
+	}
+	markers, err := backend.listData("takeover", "app", "stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(markers) != 0 {
+		t.Fatalf("expected the takeover marker to be removed, got %v", markers)
+	}
+
+	err = Lock(backend, "second", "dev", "deploy", "app", "stage")
+	var lockErr *LockExistsError
+	if !errors.As(err, &lockErr) || lockErr.UpdateID != "first" {
+		t.Fatalf("expected the lock of first to be reported, got %v", err)
+	}
+}
+
+// updateIDAt returns a descending id like id.Descending for the given time
+func updateIDAt(at time.Time, suffix string) string {
+	return fmt.Sprintf("%016x%08s", uint64(^at.UnixMilli()), suffix)
+}
+
+func TestRetentionPolicyExpired(t *testing.T) {
+	now := time.Now()
+	ids := []string{
+		updateIDAt(now.Add(-1*time.Hour), "1"),
+		updateIDAt(now.Add(-2*time.Hour), "2"),
+		updateIDAt(now.Add(-48*time.Hour), "3"),
+	}
+	if expired := (RetentionPolicy{Keep: 1}).Expired(ids, now); !slices.Equal(expired, ids[1:]) {
+		t.Errorf("expected all but the newest to expire, got %v", expired)
+	}
+	if expired := (RetentionPolicy{Keep: 1, MaxAge: 24 * time.Hour}).Expired(ids, now); !slices.Equal(expired, ids[2:]) {
+		t.Errorf("expected only the update older than a day to expire, got %v", expired)
+	}
+	if expired := (RetentionPolicy{MaxAge: time.Minute}).Expired(ids, now); !slices.Equal(expired, ids[1:]) {
+		t.Errorf("expected the newest update to be kept, got %v", expired)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	put := func(backend Home, updateID string, snapshot bool) {
+		if snapshot {
+			backend.putData("snapshot", "app", "production/"+updateID, strings.NewReader("{}"))
+		}
+		PutSummary(backend, "app", "production", updateID, Summary{})
+		PutUpdate(backend, "app", "production", Update{ID: updateID})
+	}
+	has := func(backend Home, key, updateID string) bool {
+		reader, _ := backend.getData(key, "app", "production/"+updateID)
+		return reader != nil
+	}
+
+	t.Run("keep", func(t *testing.T) {
+		backend := newMemoryHome()
+		oldest := updateIDAt(now.Add(-3*time.Hour), "1")
+		older := updateIDAt(now.Add(-2*time.Hour), "2")
+		newest := updateIDAt(now.Add(-1*time.Hour), "3")
+		failed := updateIDAt(now.Add(-30*time.Minute), "4")
+		put(backend, oldest, true)
+		put(backend, older, true)
+		put(backend, newest, true)
+		put(backend, failed, false)
+		// gc takes the lock itself, which records an update without a snapshot
+		gc := updateIDAt(now, "5")
+		if err := Lock(backend, gc, "dev", "gc", "app", "production"); err != nil {
+			t.Fatal(err)
+		}
+
+		expired, err := Prune(backend, "app", "production", RetentionPolicy{Keep: 1}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(expired, []string{older, oldest}) {
+			t.Fatalf("expected the older snapshots to be pruned, got %v", expired)
+		}
+		for _, key := range []string{"snapshot", "summary", "update"} {
+			if !has(backend, key, newest) {
+				t.Errorf("expected the %s of the newest snapshot to be kept", key)
+			}
+			if has(backend, key, older) {
+				t.Errorf("expected the %s of an older snapshot to be removed", key)
+			}
+		}
+		if !has(backend, "update", failed) || !has(backend, "update", gc) {
+			t.Error("expected the updates newer than the kept snapshot to be kept")
+		}
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		backend := newMemoryHome()
+		last := updateIDAt(now.Add(-40*24*time.Hour), "1")
+		previous := updateIDAt(now.Add(-50*24*time.Hour), "2")
+		failed := updateIDAt(now.Add(-60*24*time.Hour), "3")
+		put(backend, last, true)
+		put(backend, previous, true)
+		put(backend, failed, false)
+		gc := updateIDAt(now, "4")
+		if err := Lock(backend, gc, "dev", "gc", "app", "production"); err != nil {
+			t.Fatal(err)
+		}
+
+		expired, err := Prune(backend, "app", "production", RetentionPolicy{MaxAge: 30 * 24 * time.Hour}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(expired, []string{previous, failed}) {
+			t.Fatalf("expected the last deploy to be kept, got %v", expired)
+		}
+		if !has(backend, "snapshot", last) {
+			t.Error("expected the snapshot of the last deploy to be kept")
+		}
+		if !has(backend, "update", gc) {
+			t.Error("expected the update of the lock to be kept")
+		}
+	})
+}
+
+func TestRollback(t *testing.T) {
+	backend := newMemoryHome()
+	first := updateIDAt(time.Now().Add(-2*time.Hour), "1")
+	second := updateIDAt(time.Now().Add(-1*time.Hour), "2")
+	backend.putData("snapshot", "app", "production/"+first, strings.NewReader(`{"version":1}`))
+	backend.putData("snapshot", "app", "production/"+second, strings.NewReader(`{"version":2}`))
+	backend.putData("app", "app", "production", strings.NewReader(`{"version":2}`))
+	rollback := func(updateID, target string) (Summary, error) {
+		summary := Summary{
+			Version:     "dev",
+			Command:     "rollback",
+			UpdateID:    updateID,
+			TimeStarted: time.Now().UTC().Format(time.RFC3339),
+		}
+		path := filepath.Join(t.TempDir(), "state.json")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = PullSnapshot(backend, "app", "production", target, file)
+		file.Close()
+		if err == nil {
+			err = PushState(backend, summary.UpdateID, "app", "production", path)
+		}
+		if putErr := PutRollback(backend, "app", "production", summary, err); putErr != nil {
+			t.Fatal(putErr)
+		}
+		return summary, err
+	}
+	state := func() string {
+		reader, _ := backend.getData("app", "app", "production")
+		data, _ := io.ReadAll(reader)
+		return string(data)
+	}
+
+	summary, err := rollback(updateIDAt(time.Now().Add(-30*time.Minute), "3"), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state() != `{"version":1}` {
+		t.Fatalf("expected the state of the first update, got %s", state())
+	}
+	var snapshot bytes.Buffer
+	if err := PullSnapshot(backend, "app", "production", summary.UpdateID, &snapshot); err != nil || snapshot.String() != `{"version":1}` {
+		t.Fatal("expected the rollback to store a snapshot so it can be undone")
+	}
+	update, err := GetUpdate(backend, "app", "production", summary.UpdateID)
+	if err != nil || update == nil || update.Command != "rollback" || len(update.Errors) != 0 || update.TimeCompleted == "" {
+		t.Fatalf("expected a completed rollback to be recorded, got %+v", update)
+	}
+
+	summary, err = rollback(updateIDAt(time.Now(), "4"), "missing")
+	if !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("expected ErrSnapshotNotFound, got %v", err)
+	}
+	if state() != `{"version":1}` {
+		t.Fatal("expected a failed rollback to leave the state alone")
+	}
+	recorded, err := GetSummary(backend, "app", "production", summary.UpdateID)
+	if err != nil || recorded == nil || len(recorded.Errors) != 1 || recorded.Errors[0].Message != ErrSnapshotNotFound.Error() {
+		t.Fatalf("expected the failure in the summary, got %+v", recorded)
+	}
+	update, err = GetUpdate(backend, "app", "production", summary.UpdateID)
+	if err != nil || update == nil || len(update.Errors) != 1 {
+		t.Fatalf("expected the failure in the update, got %+v", update)
+	}
+	history, err := ListUpdates(backend, "app", "production")
+	if err != nil || len(history) != 2 || history[0] != summary.UpdateID {
+		t.Fatalf("expected both rollbacks in the history, newest first, got %v", history)
+	}
+}
@@ -0,0 +1,294 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/sig"
+	"golang.org/x/exp/slog"
+	"golang.org/x/sync/errgroup"
+)
+
+type rotation struct {
+	Passphrase string `json:"passphrase"`
+}
+
+// RotatePassphrase generates a new passphrase for the stage and re-encrypts the
+// secrets, the state and every snapshot with it. The new passphrase is first
+// recorded in a rotation marker sealed with the old one, so an interrupted
+// rotation is resumed the next time this runs. The passphrase itself is only
+// swapped once all the data has been rewritten.
+func RotatePassphrase(backend Home, app, stage string) error {
+	slog.Info("rotating passphrase", "app", app, "stage", stage)
+	if s3Home, ok := backend.(*S3Home); ok && s3Home.passphrase != "" {
+		return ErrPassphraseFromEnv
+	}
+	current, err := Passphrase(backend, app, stage)
+	if err != nil {
+		return err
+	}
+
+	next := ""
+	reader, err := backend.getData("rotation", app, stage)
+	if err != nil {
+		return err
+	}
+	if reader != nil {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		var pending rotation
+		unsealed, err := unseal(current, data)
+		if err == nil && json.Unmarshal(unsealed, &pending) == nil {
+			slog.Info("resuming interrupted rotation")
+			next = pending.Passphrase
+		}
+	}
+	if next == "" {
+		random := make([]byte, 32)
+		_, err := rand.Read(random)
+		if err != nil {
+			return err
+		}
+		next = base64.StdEncoding.EncodeToString(random)
+		marker, err := json.Marshal(rotation{Passphrase: next})
+		if err != nil {
+			return err
+		}
+		marker, err = seal(current, marker)
+		if err != nil {
+			return err
+		}
+		err = backend.putData("rotation", app, stage, bytes.NewReader(marker))
+		if err != nil {
+			return err
+		}
+	}
+
+	states, err := newStateCrypter(current, next)
+	if err != nil {
+		return err
+	}
+
+	err = rotateSecrets(backend, app, stage, current, next)
+	if err != nil {
+		return err
+	}
+	err = rotateState(backend, states, "app", app, stage)
+	if err != nil {
+		return err
+	}
+	snapshots, err := backend.listData("snapshot", app, stage)
+	if err != nil {
+		return err
+	}
+	var group errgroup.Group
+	group.SetLimit(10)
+	for _, updateID := range snapshots {
+		updateID := updateID
+		group.Go(func() error {
+			return rotateState(backend, states, "snapshot", app, stage+"/"+updateID)
+		})
+	}
+	err = group.Wait()
+	if err != nil {
+		return err
+	}
+
+	err = backend.updatePassphrase(app, stage, next)
+	if err != nil {
+		return err
+	}
+	if cache, ok := passphraseCache[backend]; ok {
+		delete(cache, app+stage)
+	}
+	return backend.removeData("rotation", app, stage)
+}
+
+// stages with a data key don't seal their secrets with the passphrase
+func rotateSecrets(backend Home, app, stage, current, next string) error {
+	record, err := getDataKey(backend, app, stage)
+	if err != nil || record != nil {
+		return err
+	}
+	for _, key := range sealedKeys {
+		err := resealData(backend, key, app, stage, current, next)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rotateState(backend Home, crypter *stateCrypter, key, app, stage string) error {
+	reader, err := backend.getData(key, app, stage)
+	if err != nil {
+		return err
+	}
+	if reader == nil {
+		return nil
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	rotated, changed, err := crypter.rotate(data)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", key, stage, err)
+	}
+	if !changed {
+		return nil
+	}
+	return backend.putData(key, app, stage, bytes.NewReader(rotated))
+}
+
+// stateCrypter re-encrypts the secret values in a pulumi checkpoint that uses
+// the passphrase secrets provider.
+type stateCrypter struct {
+	from      string
+	to        string
+	next      config.Crypter
+	nextState json.RawMessage
+	decrypter map[string]config.Crypter
+	mutex     sync.Mutex
+}
+
+func newStateCrypter(from, to string) (*stateCrypter, error) {
+	salt := make([]byte, 8)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	next := config.NewSymmetricCrypterFromPassphrase(to, salt)
+	check, err := next.EncryptValue(context.Background(), "pulumi")
+	if err != nil {
+		return nil, err
+	}
+	nextState, err := json.Marshal(map[string]string{
+		"salt": "v1:" + base64.StdEncoding.EncodeToString(salt) + ":" + check,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stateCrypter{
+		from:      from,
+		to:        to,
+		next:      next,
+		nextState: nextState,
+		decrypter: map[string]config.Crypter{},
+	}, nil
+}
+
+func (s *stateCrypter) rotate(data []byte) ([]byte, bool, error) {
+	var checkpoint map[string]interface{}
+	err := json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, false, err
+	}
+	inner, ok := checkpoint["checkpoint"].(map[string]interface{})
+	if !ok {
+		return data, false, nil
+	}
+	latest, ok := inner["latest"].(map[string]interface{})
+	if !ok {
+		return data, false, nil
+	}
+	providers, ok := latest["secrets_providers"].(map[string]interface{})
+	if !ok || providers["type"] != "passphrase" {
+		return data, false, nil
+	}
+	state, _ := providers["state"].(map[string]interface{})
+	salt, _ := state["salt"].(string)
+	decrypter, err := s.decrypterFor(salt)
+	if err != nil {
+		return nil, false, err
+	}
+	if decrypter == nil {
+		// already encrypted with the new passphrase
+		return data, false, nil
+	}
+
+	ctx := context.Background()
+	var walk func(value interface{}) error
+	walk = func(value interface{}) error {
+		switch cast := value.(type) {
+		case map[string]interface{}:
+			if cast[sig.Key] == sig.Secret {
+				if ciphertext, ok := cast["ciphertext"].(string); ok {
+					plaintext, err := decrypter.DecryptValue(ctx, ciphertext)
+					if err != nil {
+						return err
+					}
+					encrypted, err := s.next.EncryptValue(ctx, plaintext)
+					if err != nil {
+						return err
+					}
+					cast["ciphertext"] = encrypted
+					return nil
+				}
+			}
+			for _, item := range cast {
+				if err := walk(item); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for _, item := range cast {
+				if err := walk(item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = walk(latest)
+	if err != nil {
+		return nil, false, err
+	}
+	providers["state"] = s.nextState
+	result, err := json.MarshalIndent(checkpoint, "", "    ")
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+// decrypterFor returns the crypter for a salt written with the old passphrase,
+// or nil if the salt was written with the new one by an interrupted rotation.
+// Deriving keys is slow so they are cached per salt.
+func (s *stateCrypter) decrypterFor(salt string) (config.Crypter, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if match, ok := s.decrypter[salt]; ok {
+		return match, nil
+	}
+	parts := strings.SplitN(salt, ":", 3)
+	if len(parts) != 3 || parts[0] != "v1" {
+		return nil, fmt.Errorf("unknown secrets provider state")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	crypter := config.NewSymmetricCrypterFromPassphrase(s.from, saltBytes)
+	if check, err := crypter.DecryptValue(ctx, parts[2]); err != nil || check != "pulumi" {
+		crypter = nil
+		next := config.NewSymmetricCrypterFromPassphrase(s.to, saltBytes)
+		nextCheck, err := next.DecryptValue(ctx, parts[2])
+		if err != nil || nextCheck != "pulumi" {
+			return nil, fmt.Errorf("state is encrypted with an unknown passphrase")
+		}
+	}
+	s.decrypter[salt] = crypter
+	return crypter, nil
+}
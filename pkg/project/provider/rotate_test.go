@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sst/ion/pkg/flag"
+)
+
+// failingHome fails to write the given key, like a rotation that gets
+// interrupted halfway through
+type failingHome struct {
+	*memoryHome
+	fail string
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (f *failingHome) putData(key, app, stage string, data io.Reader) error {
+	if key == f.fail {
+		return errWriteFailed
+	}
+	return f.memoryHome.putData(key, app, stage, data)
+}
+
+// rotationFixture stores a stage with a secret, a state and a snapshot that
+// are all encrypted with the passphrase
+func rotationFixture(t *testing.T, backend Home) string {
+	passphrase := randomPassphrase(t)
+	backend.setPassphrase("app", "production", passphrase)
+	backend.putData("app", "app", "production", bytes.NewReader(checkpoint(t, passphrase, "hunter2")))
+	backend.putData("snapshot", "app", "production/01", bytes.NewReader(checkpoint(t, passphrase, "hunter1")))
+	if err := PutSecrets(backend, "dev", "app", "production", map[string]string{"Token": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	return passphrase
+}
+
+// checkRotated checks everything in the stage is readable with the new
+// passphrase
+func checkRotated(t *testing.T, backend *memoryHome, old string) {
+	passphrase, _ := backend.getPassphrase("app", "production")
+	if passphrase == old {
+		t.Fatal("expected the passphrase to change")
+	}
+	if decryptCheckpoint(t, passphrase, backend.data["app/app/production"]) != "hunter2" {
+		t.Error("expected the state to be encrypted with the new passphrase")
+	}
+	if decryptCheckpoint(t, passphrase, backend.data["snapshot/app/production/01"]) != "hunter1" {
+		t.Error("expected the snapshot to be encrypted with the new passphrase")
+	}
+	if _, ok := backend.data["rotation/app/production"]; ok {
+		t.Error("expected the rotation marker to be removed")
+	}
+}
+
+func TestRotatePassphrase(t *testing.T) {
+	backend := newMemoryHome()
+	old := rotationFixture(t, backend)
+
+	if err := RotatePassphrase(backend, "app", "production"); err != nil {
+		t.Fatal(err)
+	}
+	checkRotated(t, backend, old)
+	passphrase, _ := backend.getPassphrase("app", "production")
+	if _, err := unseal(passphrase, backend.data["secret/app/production"]); err != nil {
+		t.Error("expected the secrets to be sealed with the new passphrase")
+	}
+	secrets, err := GetSecrets(backend, "app", "production")
+	if err != nil || secrets["Token"] != "abc" {
+		t.Fatalf("expected the secrets to be readable after rotating, got %v %v", secrets, err)
+	}
+}
+
+func TestRotatePassphraseResume(t *testing.T) {
+	memory := newMemoryHome()
+	backend := &failingHome{memoryHome: memory}
+	old := rotationFixture(t, backend)
+	backend.fail = "snapshot"
+
+	err := RotatePassphrase(backend, "app", "production")
+	if !errors.Is(err, errWriteFailed) {
+		t.Fatalf("expected the rotation to fail, got %v", err)
+	}
+	if passphrase, _ := backend.getPassphrase("app", "production"); passphrase != old {
+		t.Fatal("expected the passphrase to be kept until everything is rotated")
+	}
+	unsealed, err := unseal(old, memory.data["rotation/app/production"])
+	if err != nil {
+		t.Fatal("expected the rotation marker to be sealed with the old passphrase")
+	}
+	var pending rotation
+	if err := json.Unmarshal(unsealed, &pending); err != nil {
+		t.Fatal(err)
+	}
+	if decryptCheckpoint(t, pending.Passphrase, memory.data["app/app/production"]) != "hunter2" {
+		t.Fatal("expected the state to be rotated before the failure")
+	}
+
+	backend.fail = ""
+	if err := RotatePassphrase(backend, "app", "production"); err != nil {
+		t.Fatal(err)
+	}
+	checkRotated(t, memory, old)
+	if passphrase, _ := backend.getPassphrase("app", "production"); passphrase != pending.Passphrase {
+		t.Fatal("expected the resumed rotation to use the passphrase from the marker")
+	}
+	secrets, err := GetSecrets(backend, "app", "production")
+	if err != nil || secrets["Token"] != "abc" {
+		t.Fatalf("expected the secrets to be readable after resuming, got %v %v", secrets, err)
+	}
+}
+
+func TestRotatePassphraseDataKey(t *testing.T) {
+	provider, keyfile := flag.SST_KEY_PROVIDER, flag.SST_KEYFILE
+	flag.SST_KEY_PROVIDER = "keyfile"
+	flag.SST_KEYFILE = filepath.Join(t.TempDir(), "keyfile")
+	defer func() {
+		flag.SST_KEY_PROVIDER, flag.SST_KEYFILE = provider, keyfile
+	}()
+
+	backend := newMemoryHome()
+	old := rotationFixture(t, backend)
+	if record, err := getDataKey(backend, "app", "production"); err != nil || record == nil {
+		t.Fatal("expected the secrets to be sealed with a data key")
+	}
+	sealed := backend.data["secret/app/production"]
+
+	if err := RotatePassphrase(backend, "app", "production"); err != nil {
+		t.Fatal(err)
+	}
+	checkRotated(t, backend, old)
+	if !bytes.Equal(backend.data["secret/app/production"], sealed) {
+		t.Error("expected the secrets sealed with the data key to be left alone")
+	}
+	secrets, err := GetSecrets(backend, "app", "production")
+	if err != nil || secrets["Token"] != "abc" {
+		t.Fatalf("expected the secrets to be readable after rotating, got %v %v", secrets, err)
+	}
+}
@@ -0,0 +1,268 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/flag"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Home stores state in any S3 compatible object store, like MinIO, Ceph or
+// Garage. It's configured through environment variables instead of a provider
+// so it can be used without an AWS account.
+type S3Home struct {
+	endpoint        string
+	bucket          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	passphrase      string
+	client          *s3.Client
+}
+
+func NewS3Home() *S3Home {
+	region := flag.SST_HOME_S3_REGION
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Home{
+		endpoint:        flag.SST_HOME_S3_ENDPOINT,
+		bucket:          flag.SST_HOME_S3_BUCKET,
+		region:          region,
+		accessKeyID:     flag.SST_HOME_S3_ACCESS_KEY_ID,
+		secretAccessKey: flag.SST_HOME_S3_SECRET_ACCESS_KEY,
+		passphrase:      flag.SST_HOME_S3_PASSPHRASE,
+	}
+}
+
+func (s *S3Home) Bootstrap() error {
+	if s.endpoint == "" || s.bucket == "" {
+		return util.NewReadableError(nil, "The s3 home requires the SST_HOME_S3_ENDPOINT and SST_HOME_S3_BUCKET environment variables.")
+	}
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx, func(lo *config.LoadOptions) error {
+		lo.Region = s.region
+		if s.accessKeyID != "" && s.secretAccessKey != "" {
+			lo.Credentials = credentials.NewStaticCredentialsProvider(s.accessKeyID, s.secretAccessKey, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(s.endpoint)
+		o.UsePathStyle = true
+	})
+
+	_, err = s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	if err == nil {
+		slog.Info("found existing bucket", "bucket", s.bucket)
+		return nil
+	}
+	var notFound *s3types.NotFound
+	if !errors.As(err, &notFound) {
+		return err
+	}
+	slog.Info("creating new bucket", "bucket", s.bucket)
+	_, err = s.client.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	return err
+}
+
+func (s *S3Home) pathForData(key, app, stage string) string {
+	return path.Join(key, app, fmt.Sprintf("%v.json", stage))
+}
+
+func (s *S3Home) getData(key, app, stage string) (io.Reader, error) {
+	return s3GetData(s.client, s.bucket, s.pathForData(key, app, stage))
+}
+
+func (s *S3Home) putData(key, app, stage string, data io.Reader) error {
+	return s3PutData(s.client, s.bucket, s.pathForData(key, app, stage), data)
+}
+
+func (s *S3Home) getDataVersion(key, app, stage string) (io.Reader, string, error) {
+	return s3GetDataVersion(s.client, s.bucket, s.pathForData(key, app, stage))
+}
+
+func (s *S3Home) replaceData(key, app, stage, version string, data io.Reader) error {
+	return s3ReplaceData(s.client, s.bucket, s.pathForData(key, app, stage), version, data)
+}
+
+func (s *S3Home) createData(key, app, stage string, data io.Reader) error {
+	return s3CreateData(s.client, s.bucket, s.pathForData(key, app, stage), data)
+}
+
+func (s *S3Home) removeData(key, app, stage string) error {
+	return s3RemoveData(s.client, s.bucket, s.pathForData(key, app, stage))
+}
+
+func (s *S3Home) listData(key, app, stage string) ([]string, error) {
+	return s3ListData(s.client, s.bucket, path.Join(key, app, stage)+"/")
+}
+
+// when SST_HOME_S3_PASSPHRASE is set it's used as is for every stage and
+// never written to the bucket
+func (s *S3Home) setPassphrase(app, stage string, passphrase string) error {
+	if s.passphrase != "" {
+		return nil
+	}
+	return s.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
+}
+
+var ErrPassphraseFromEnv = fmt.Errorf("passphrase is set through SST_HOME_S3_PASSPHRASE")
+
+func (s *S3Home) updatePassphrase(app, stage string, passphrase string) error {
+	if s.passphrase != "" {
+		return ErrPassphraseFromEnv
+	}
+	return s.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
+}
+
+func (s *S3Home) getPassphrase(app, stage string) (string, error) {
+	if s.passphrase != "" {
+		return s.passphrase, nil
+	}
+	data, err := s.getData("passphrase", app, stage)
+	if err != nil {
+		return "", err
+	}
+	if data == nil {
+		return "", nil
+	}
+	read, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	return string(read), nil
+}
+
+func s3GetData(client *s3.Client, bucket, key string) (io.Reader, error) {
+	data, _, err := s3GetDataVersion(client, bucket, key)
+	return data, err
+}
+
+// s3GetDataVersion also returns the ETag of the object so it can be replaced
+// with s3ReplaceData
+func s3GetDataVersion(client *s3.Client, bucket, key string) (io.Reader, string, error) {
+	result, err := client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			if apiErr.ErrorCode() == "NoSuchBucket" {
+				return nil, "", ErrBucketMissing
+			}
+		}
+		var nsk *s3types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	return result.Body, aws.ToString(result.ETag), nil
+}
+
+func s3PutData(client *s3.Client, bucket, key string, data io.Reader) error {
+	_, err := client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        data,
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// s3CreateData uses a conditional write so only one writer can create the key
+func s3CreateData(client *s3.Client, bucket, key string, data io.Reader) error {
+	_, err := client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        data,
+		ContentType: aws.String("application/json"),
+	}, func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, smithyhttp.AddHeaderValue("If-None-Match", "*"))
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			if apiErr.ErrorCode() == "PreconditionFailed" || apiErr.ErrorCode() == "ConditionalRequestConflict" {
+				return errDataExists
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// s3ReplaceData uses a conditional write so the key is only replaced if its
+// ETag still matches
+func s3ReplaceData(client *s3.Client, bucket, key, etag string, data io.Reader) error {
+	_, err := client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        data,
+		ContentType: aws.String("application/json"),
+	}, func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, smithyhttp.AddHeaderValue("If-Match", etag))
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "PreconditionFailed", "ConditionalRequestConflict", "NoSuchKey":
+				return errDataChanged
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func s3RemoveData(client *s3.Client, bucket, key string) error {
+	_, err := client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func s3ListData(client *s3.Client, bucket, prefix string) ([]string, error) {
+	result := []string{}
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(object.Key), prefix)
+			result = append(result, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"golang.org/x/exp/slog"
+)
+
+// SecretVersion is a single value a secret had. The history of a secret is
+// stored oldest first and is never rewritten, only appended to.
+type SecretVersion struct {
+	Version    int    `json:"version"`
+	Value      string `json:"value"`
+	Removed    bool   `json:"removed,omitempty"`
+	Restored   int    `json:"restored,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Identity   string `json:"identity,omitempty"`
+	Time       string `json:"time"`
+	CLIVersion string `json:"cliVersion,omitempty"`
+}
+
+type SecretHistory map[string][]SecretVersion
+
+var ErrSecretVersionNotFound = fmt.Errorf("secret version not found")
+
+func GetSecretHistory(backend Home, app, stage string) (SecretHistory, error) {
+	if stage == "" {
+		stage = "_fallback"
+	}
+	history := SecretHistory{}
+	err := getData(backend, "secrethistory", app, stage, true, &history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func putSecretHistory(backend Home, app, stage string, history SecretHistory) error {
+	if stage == "" {
+		stage = "_fallback"
+	}
+	return putData(backend, "secrethistory", app, stage, true, history)
+}
+
+// recordSecrets appends a version for every secret that changed between the
+// current and the next set of secrets. Secrets that were set before history
+// was recorded get their current value as the first version.
+func recordSecrets(backend Home, version, app, stage string, current, next map[string]string, restored int) error {
+	history, err := GetSecretHistory(backend, app, stage)
+	if err != nil {
+		return err
+	}
+	author, identity := secretAuthor(backend)
+	now := time.Now().UTC().Format(time.RFC3339)
+	changed := false
+
+	names := map[string]bool{}
+	for name := range current {
+		names[name] = true
+	}
+	for name := range next {
+		names[name] = true
+	}
+	for name := range names {
+		before, existed := current[name]
+		after, exists := next[name]
+		if existed == exists && before == after {
+			continue
+		}
+		versions := history[name]
+		if len(versions) == 0 && existed {
+			versions = append(versions, SecretVersion{
+				Version: 1,
+				Value:   before,
+				Time:    now,
+			})
+		}
+		entry := SecretVersion{
+			Version:    len(versions) + 1,
+			Value:      after,
+			Removed:    !exists,
+			Restored:   restored,
+			Author:     author,
+			Identity:   identity,
+			Time:       now,
+			CLIVersion: version,
+		}
+		history[name] = append(versions, entry)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	slog.Info("recording secret history", "app", app, "stage", stage)
+	return putSecretHistory(backend, app, stage, history)
+}
+
+// RestoreSecret sets a secret back to the value it had in a previous version.
+// The restore is recorded as a new version.
+func RestoreSecret(backend Home, version, app, stage, name string, target int) (*SecretVersion, error) {
+	history, err := GetSecretHistory(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	var match *SecretVersion
+	for _, item := range history[name] {
+		if item.Version == target {
+			item := item
+			match = &item
+		}
+	}
+	if match == nil {
+		return nil, ErrSecretVersionNotFound
+	}
+	secrets, err := GetSecrets(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	if match.Removed {
+		delete(secrets, name)
+	} else {
+		secrets[name] = match.Value
+	}
+	err = putSecrets(backend, version, app, stage, secrets, target)
+	if err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+type identityHome interface {
+	identity() (string, error)
+}
+
+// secretAuthor returns the git user making the change along with the cloud
+// identity of the home, if it has one.
+func secretAuthor(backend Home) (string, string) {
+	author := ""
+	output, err := exec.Command("git", "config", "user.email").Output()
+	if err == nil {
+		author = strings.TrimSpace(string(output))
+	}
+	if author == "" {
+		if current, err := user.Current(); err == nil {
+			author = current.Username
+		}
+		if hostname, err := os.Hostname(); err == nil && author != "" {
+			author = author + "@" + hostname
+		}
+	}
+	identity := ""
+	if match, ok := backend.(identityHome); ok {
+		identity, err = match.identity()
+		if err != nil {
+			slog.Info("could not get identity", "err", err)
+		}
+	}
+	return author, identity
+}
+
+func (a *AwsHome) identity() (string, error) {
+	result, err := sts.NewFromConfig(a.provider.config).GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(result.Arn), nil
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRestoreSecret(t *testing.T) {
+	backend := newMemoryHome()
+	backend.setPassphrase("app", "production", randomPassphrase(t))
+	set := func(secrets map[string]string) {
+		if err := PutSecrets(backend, "dev", "app", "production", secrets); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set(map[string]string{"Token": "first", "Other": "kept"})
+	set(map[string]string{"Token": "second", "Other": "kept"})
+
+	history, err := GetSecretHistory(backend, "app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history["Token"]) != 2 || history["Token"][0].Value != "first" || history["Token"][1].Value != "second" {
+		t.Fatalf("expected both values in the history, got %+v", history["Token"])
+	}
+	if len(history["Other"]) != 1 {
+		t.Fatalf("expected only changes to be recorded, got %+v", history["Other"])
+	}
+
+	restored, err := RestoreSecret(backend, "dev", "app", "production", "Token", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Value != "first" {
+		t.Fatalf("expected the first version to be restored, got %q", restored.Value)
+	}
+	secrets, err := GetSecrets(backend, "app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets["Token"] != "first" || secrets["Other"] != "kept" {
+		t.Fatalf("expected only the restored secret to change, got %v", secrets)
+	}
+
+	history, err = GetSecretHistory(backend, "app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := history["Token"]
+	if len(versions) != 3 {
+		t.Fatalf("expected the restore to be recorded as a new version, got %+v", versions)
+	}
+	latest := versions[2]
+	if latest.Version != 3 || latest.Value != "first" || latest.Restored != 1 || latest.CLIVersion != "dev" {
+		t.Fatalf("expected the restore of version 1 to be recorded, got %+v", latest)
+	}
+
+	_, err = RestoreSecret(backend, "dev", "app", "production", "Token", 10)
+	if !errors.Is(err, ErrSecretVersionNotFound) {
+		t.Fatalf("expected ErrSecretVersionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// Report is how long every resource took in the last deploy of a stage
+type Report struct {
+	App       string           `json:"app"`
+	Stage     string           `json:"stage"`
+	Command   string           `json:"command"`
+	Started   time.Time        `json:"started"`
+	Completed time.Time        `json:"completed"`
+	Resources []ReportResource `json:"resources"`
+	// CriticalPath is the chain of dependencies and parents that finished
+	// last, starting with the first resource in it
+	CriticalPath []string `json:"criticalPath"`
+}
+
+type ReportResource struct {
+	URN          string         `json:"urn"`
+	Type         string         `json:"type"`
+	Op           apitype.OpType `json:"op"`
+	Parent       string         `json:"parent,omitempty"`
+	Dependencies []string       `json:"dependencies,omitempty"`
+	Started      time.Time      `json:"started"`
+	Completed    time.Time      `json:"completed"`
+	Failed       bool           `json:"failed,omitempty"`
+}
+
+func (r *ReportResource) Duration() time.Duration {
+	return r.Completed.Sub(r.Started)
+}
+
+// Wall is how long the whole command took
+func (r *Report) Wall() time.Duration {
+	return r.Completed.Sub(r.Started)
+}
+
+// Total is the time all the resources took added together
+func (r *Report) Total() time.Duration {
+	total := time.Duration(0)
+	for _, resource := range r.Resources {
+		total += resource.Duration()
+	}
+	return total
+}
+
+// Slowest returns the resources that took the longest, slowest first
+func (r *Report) Slowest(limit int) []ReportResource {
+	sorted := append([]ReportResource{}, r.Resources...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Duration() > sorted[j].Duration()
+	})
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+func (r *Report) Resource(urn string) *ReportResource {
+	for i := range r.Resources {
+		if r.Resources[i].URN == urn {
+			return &r.Resources[i]
+		}
+	}
+	return nil
+}
+
+// Waited is how long the resource waited on its dependencies since the
+// command started
+func (r *Report) Waited(resource *ReportResource) time.Duration {
+	ready := r.Started
+	for _, urn := range resource.Dependencies {
+		dependency := r.Resource(urn)
+		if dependency != nil && dependency.Completed.After(ready) {
+			ready = dependency.Completed
+		}
+	}
+	return ready.Sub(r.Started)
+}
+
+// reportTimer tracks when each resource starts and completes from the engine
+// events
+type reportTimer struct {
+	lock      sync.Mutex
+	resources map[string]*ReportResource
+}
+
+func newReportTimer() *reportTimer {
+	return &reportTimer{resources: map[string]*ReportResource{}}
+}
+
+func (t *reportTimer) start(event *apitype.ResourcePreEvent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	metadata := event.Metadata
+	// components only group their children, the time is spent in the
+	// children
+	state := metadata.New
+	if state == nil {
+		state = metadata.Old
+	}
+	if state == nil || !state.Custom || metadata.Op == apitype.OpSame {
+		return
+	}
+	if _, ok := t.resources[metadata.URN]; ok {
+		return
+	}
+	t.resources[metadata.URN] = &ReportResource{
+		URN:     metadata.URN,
+		Type:    metadata.Type,
+		Op:      metadata.Op,
+		Parent:  state.Parent,
+		Started: time.Now(),
+	}
+}
+
+func (t *reportTimer) complete(urn string, failed bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	resource, ok := t.resources[urn]
+	if !ok {
+		return
+	}
+	resource.Completed = time.Now()
+	resource.Failed = failed
+}
+
+// report builds the report with the dependencies from the state after the
+// command completed. A resource waits on its parent as well as its
+// dependencies, and components are followed through to the resources they
+// wait on since they aren't timed themselves.
+func (t *reportTimer) report(started time.Time, state []apitype.ResourceV3) *Report {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	report := &Report{
+		Started:      started,
+		Completed:    time.Now(),
+		Resources:    []ReportResource{},
+		CriticalPath: []string{},
+	}
+	edges := map[string][]string{}
+	for _, resource := range state {
+		urn := string(resource.URN)
+		for _, dependency := range resource.Dependencies {
+			edges[urn] = append(edges[urn], string(dependency))
+		}
+		if resource.Parent != "" {
+			edges[urn] = append(edges[urn], string(resource.Parent))
+		}
+	}
+	dependencies := map[string][]string{}
+	for urn := range t.resources {
+		seen := map[string]bool{urn: true}
+		queue := append([]string{}, edges[urn]...)
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			if _, ok := t.resources[next]; ok {
+				dependencies[urn] = append(dependencies[urn], next)
+				continue
+			}
+			queue = append(queue, edges[next]...)
+		}
+		sort.Strings(dependencies[urn])
+	}
+	for _, resource := range t.resources {
+		if resource.Completed.IsZero() {
+			resource.Completed = report.Completed
+		}
+		resource.Dependencies = dependencies[resource.URN]
+		report.Resources = append(report.Resources, *resource)
+	}
+	sort.Slice(report.Resources, func(i, j int) bool {
+		return report.Resources[i].Started.Before(report.Resources[j].Started)
+	})
+	report.CriticalPath = criticalPath(report)
+	return report
+}
+
+// criticalPath starts at the resource that completed last and keeps following
+// the dependency that completed last
+func criticalPath(report *Report) []string {
+	var current *ReportResource
+	for i := range report.Resources {
+		if current == nil || report.Resources[i].Completed.After(current.Completed) {
+			current = &report.Resources[i]
+		}
+	}
+	path := []string{}
+	seen := map[string]bool{}
+	for current != nil && !seen[current.URN] {
+		seen[current.URN] = true
+		path = append([]string{current.URN}, path...)
+		var next *ReportResource
+		for _, urn := range current.Dependencies {
+			dependency := report.Resource(urn)
+			if dependency != nil && (next == nil || dependency.Completed.After(next.Completed)) {
+				next = dependency
+			}
+		}
+		current = next
+	}
+	return path
+}
+
+func (p Project) PathReport() string {
+	return filepath.Join(p.PathWorkingDir(), "report", p.app.Stage+".json")
+}
+
+func (p *Project) writeReport(report *Report) error {
+	report.App = p.app.Name
+	report.Stage = p.app.Stage
+	err := os.MkdirAll(filepath.Dir(p.PathReport()), 0755)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.PathReport(), data, 0644)
+}
+
+// ReadReport returns the report of the last deploy of the stage
+func (p *Project) ReadReport() (*Report, error) {
+	data, err := os.ReadFile(p.PathReport())
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	err = json.Unmarshal(data, &report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
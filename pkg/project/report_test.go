@@ -0,0 +1,71 @@
+package project
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+func TestCriticalPath(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	report := &Report{
+		Started:   start,
+		Completed: at(100),
+		Resources: []ReportResource{
+			{URN: "role", Started: at(1), Completed: at(5)},
+			{URN: "bucket", Started: at(1), Completed: at(30)},
+			{URN: "function", Started: at(5), Completed: at(20), Dependencies: []string{"role"}},
+			{URN: "cdn", Started: at(30), Completed: at(90), Dependencies: []string{"bucket", "function"}},
+			{URN: "dns", Started: at(20), Completed: at(25), Dependencies: []string{"function"}},
+		},
+	}
+	path := criticalPath(report)
+	expected := []string{"bucket", "cdn"}
+	if len(path) != len(expected) || path[0] != expected[0] || path[1] != expected[1] {
+		t.Fatalf("expected %v, got %v", expected, path)
+	}
+	if waited := report.Waited(report.Resource("cdn")); waited != 30*time.Second {
+		t.Fatalf("expected cdn to wait 30s, got %v", waited)
+	}
+	if total := report.Total(); total != 113*time.Second {
+		t.Fatalf("expected 113s of resource time, got %v", total)
+	}
+	if slowest := report.Slowest(1); slowest[0].URN != "cdn" {
+		t.Fatalf("expected cdn to be the slowest, got %v", slowest[0].URN)
+	}
+}
+
+func TestReportParents(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	timer := newReportTimer()
+	timer.resources["bucket"] = &ReportResource{URN: "bucket", Started: at(1), Completed: at(40)}
+	timer.resources["role"] = &ReportResource{URN: "role", Started: at(1), Completed: at(5)}
+	timer.resources["function"] = &ReportResource{URN: "function", Parent: "api", Started: at(40), Completed: at(60)}
+	// the function is created inside a component that waits on the bucket
+	state := []apitype.ResourceV3{
+		{URN: "stack"},
+		{URN: "bucket", Parent: "stack"},
+		{URN: "role", Parent: "stack"},
+		{URN: "api", Parent: "stack", Dependencies: []resource.URN{"bucket"}},
+		{URN: "function", Parent: "api", Dependencies: []resource.URN{"role"}},
+	}
+	report := timer.report(start, state)
+	if !slices.Equal(report.Resource("function").Dependencies, []string{"bucket", "role"}) {
+		t.Fatalf("expected the function to wait on the bucket through its parent, got %v", report.Resource("function").Dependencies)
+	}
+	if !slices.Equal(report.CriticalPath, []string{"bucket", "function"}) {
+		t.Fatalf("expected the critical path to go through the parent, got %v", report.CriticalPath)
+	}
+	if waited := report.Waited(report.Resource("function")); waited != 40*time.Second {
+		t.Fatalf("expected the function to wait 40s, got %v", waited)
+	}
+}
@@ -0,0 +1,52 @@
+package project
+
+import (
+	"slices"
+	"time"
+)
+
+type RetryEvent struct {
+	Attempt int
+	URNs    []string
+	Delay   time.Duration
+}
+
+const maxRetryDelay = time.Minute
+
+// retryDelay backs off exponentially starting at 5 seconds
+func retryDelay(attempt int) time.Duration {
+	delay := 5 * time.Second << (attempt - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// transientURNs returns the resources that failed, if every error is one the
+// common errors registry marks as transient. Otherwise running again won't
+// fix it and it returns false.
+func transientURNs(errors []Error) ([]string, bool) {
+	if len(errors) == 0 {
+		return nil, false
+	}
+	registry, err := CommonErrors()
+	if err != nil {
+		return nil, false
+	}
+	urns := []string{}
+	for _, item := range errors {
+		if item.URN == "" {
+			return nil, false
+		}
+		transient := slices.ContainsFunc(registry, func(commonError CommonError) bool {
+			return commonError.Transient && commonError.Match(item.Message, item.URN)
+		})
+		if !transient {
+			return nil, false
+		}
+		if !slices.Contains(urns, item.URN) {
+			urns = append(urns, item.URN)
+		}
+	}
+	return urns, true
+}
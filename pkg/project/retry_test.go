@@ -0,0 +1,32 @@
+package project
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransientURNs(t *testing.T) {
+	api := "urn:pulumi:dev::app::sst:aws:Function$aws:lambda/function:Function::Api"
+	cdn := "urn:pulumi:dev::app::sst:aws:Router$aws:cloudfront/distribution:Distribution::Cdn"
+
+	urns, ok := transientURNs([]Error{
+		{URN: api, Message: "creating Lambda Function (api): InvalidParameterValueException: The role defined for the function cannot be assumed by Lambda."},
+		{URN: cdn, Message: "updating CloudFront Distribution (E123): PreconditionFailed: The request failed"},
+		{URN: api, Message: "TooManyRequestsException: Rate exceeded"},
+	})
+	if !ok || len(urns) != 2 || urns[0] != api || urns[1] != cdn {
+		t.Fatalf("expected both resources to be retried, got %v %v", urns, ok)
+	}
+
+	_, ok = transientURNs([]Error{
+		{URN: api, Message: "TooManyRequestsException: Rate exceeded"},
+		{URN: cdn, Message: "InvalidArgument: The parameter is invalid"},
+	})
+	if ok {
+		t.Fatal("expected no retry when an error is not transient")
+	}
+
+	if retryDelay(1) != 5*time.Second || retryDelay(2) != 10*time.Second || retryDelay(10) != maxRetryDelay {
+		t.Fatal("unexpected retry delays")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -48,9 +49,32 @@ type StackInput struct {
 	ServerPort int
 	Dev        bool
 	Verbose    bool
+	// SavePlan is where diff writes the plan to
+	SavePlan string
+	// Plan makes deploy refuse to run anything that's not in it
+	Plan *Plan
+	// AllowDestroy are the protected URNs deploy and remove may destroy
+	AllowDestroy []string
+	// Retry is how many times deploy reruns resources that failed with a
+	// transient error
+	Retry int
+	// Parallel limits how many resources deploy updates at once
+	Parallel int
+	// Replace are the URNs deploy replaces even if they haven't changed
+	Replace []string
+	// Exclude are the URNs deploy skips
+	Exclude []string
+	// ContinueOnError keeps deploying the resources that don't depend on a
+	// resource that failed
+	ContinueOnError bool
 }
 
-type ConcurrentUpdateEvent struct{}
+type ConcurrentUpdateEvent struct {
+	Created time.Time
+	Command string
+	User    string
+	Host    string
+}
 
 type ProviderDownloadEvent struct {
 	Name    string
@@ -117,54 +141,21 @@ type Error struct {
 	Help    []string `json:"help"`
 }
 
-type CommonError struct {
-	Code    string   `json:"code"`
-	Message string   `json:"message"`
-	Short   []string `json:"short"`
-	Long    []string `json:"long"`
-}
-
-var CommonErrors = []CommonError{
-	{
-		Code:    "TooManyCacheBehaviors",
-		Message: "TooManyCacheBehaviors: Your request contains more CacheBehaviors than are allowed per distribution",
-		Short: []string{
-			"There are too many top-level files and directories inside your app's public asset directory. Move some of them inside subdirectories.",
-			"Learn more about this https://sst.dev/docs/common-errors#toomanycachebehaviors",
-		},
-		Long: []string{
-			"This error usually happens to `SvelteKit`, `SolidStart`, `Nuxt`, and `Analog` components.",
-			"",
-			"CloudFront distributions have a **limit of 25 cache behaviors** per distribution. Each top-level file or directory in your frontend app's asset directory creates a cache behavior.",
-			"",
-			"For example, in the case of SvelteKit, the static assets are in the `static/` directory. If you have a file and a directory in it, it'll create 2 cache behaviors.",
-			"",
-			"```bash frame=\"none\"",
-			"static/",
-			"├── icons/       # Cache behavior for /icons/*",
-			"└── logo.png     # Cache behavior for /logo.png",
-			"```",
-			"So if you have many of these at the top-level, you'll hit the limit. You can request a limit increase through the AWS Support.",
-			"",
-			"Alternatively, you can move some of these into subdirectories. For example, moving them to an `images/` directory, will only create 1 cache behavior.",
-			"",
-			"```bash frame=\"none\"",
-			"static/",
-			"└── images/      # Cache behavior for /images/*",
-			"    ├── icons/",
-			"    └── logo.png",
-			"```",
-			"Learn more about these [CloudFront limits](https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/cloudfront-limits.html#limits-web-distributions).",
-		},
-	},
-}
-
 var ErrStackRunFailed = fmt.Errorf("stack run had errors")
 var ErrStageNotFound = fmt.Errorf("stage not found")
 var ErrPassphraseInvalid = fmt.Errorf("passphrase invalid")
 
 func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	slog.Info("running stack command", "cmd", input.Command)
+	runStarted := time.Now()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if input.Plan != nil {
+		if input.Plan.App != p.app.Name || input.Plan.Stage != p.app.Stage {
+			return fmt.Errorf("%w: plan is for %s/%s", ErrPlanMismatch, input.Plan.App, input.Plan.Stage)
+		}
+		input.Target = input.Plan.Target
+	}
 
 	bus.Publish(&StackCommandEvent{
 		App:     p.app.Name,
@@ -174,12 +165,20 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 		Version: p.Version(),
 	})
 
+	// diff and drift only preview changes, so they don't lock or write state
+	readOnly := input.Command == "diff" || input.Command == "drift"
 	updateID := id.Descending()
-	if input.Command != "diff" {
-		err := p.Lock(updateID, input.Command)
+	if !readOnly {
+		err := p.lockStage(updateID, input.Command, cancel)
 		if err != nil {
-			if err == provider.ErrLockExists {
-				bus.Publish(&ConcurrentUpdateEvent{})
+			var lockErr *provider.LockExistsError
+			if errors.As(err, &lockErr) {
+				bus.Publish(&ConcurrentUpdateEvent{
+					Created: lockErr.Created,
+					Command: lockErr.Command,
+					User:    lockErr.User,
+					Host:    lockErr.Host,
+				})
 			}
 			return err
 		}
@@ -196,7 +195,7 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 			return err
 		}
 	}
-	if input.Command != "diff" {
+	if !readOnly {
 		defer p.PushState(updateID)
 	}
 
@@ -251,6 +250,9 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	env["PULUMI_CONFIG_PASSPHRASE"] = passphrase
 	env["PULUMI_SKIP_UPDATE_CHECK"] = "true"
 	// env["PULUMI_DISABLE_AUTOMATIC_PLUGIN_ACQUISITION"] = "true"
+	if global.Offline() {
+		env["PULUMI_DISABLE_AUTOMATIC_PLUGIN_ACQUISITION"] = "true"
+	}
 	env["NODE_OPTIONS"] = "--enable-source-maps --no-deprecation"
 	// env["TMPDIR"] = p.PathLog("")
 	if input.ServerPort != 0 {
@@ -339,8 +341,9 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	providerShim = append(providerShim, fmt.Sprintf("import * as sst from \"%s\";", path.Join(p.PathPlatformDir(), "src/components")))
 
 	buildResult, err := js.Build(js.EvalOptions{
-		Dir:     p.PathRoot(),
-		Outfile: outfile,
+		Dir:        p.PathRoot(),
+		WorkingDir: p.PathWorkingDir(),
+		Outfile:    outfile,
 		Define: map[string]string{
 			"$app": string(appBytes),
 			"$cli": string(cliBytes),
@@ -350,12 +353,12 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 		Globals: strings.Join(providerShim, "\n"),
 		Code: fmt.Sprintf(`
       import { run } from "%v";
-      import mod from "%v/sst.config.ts";
+      import mod from "%v";
       const result = await run(mod.run);
       export default result;
     `,
 			path.Join(p.PathWorkingDir(), "platform/src/auto/run.ts"),
-			p.PathRoot(),
+			filepath.ToSlash(p.PathConfig()),
 		),
 	})
 	if err != nil {
@@ -368,19 +371,10 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 		defer js.Cleanup(buildResult)
 	}
 
-	var meta = map[string]interface{}{}
-	err = json.Unmarshal([]byte(buildResult.Metafile), &meta)
+	files, err := metafileInputs(buildResult.Metafile)
 	if err != nil {
 		return err
 	}
-	files := []string{}
-	for key := range meta["inputs"].(map[string]interface{}) {
-		absPath, err := filepath.Abs(key)
-		if err != nil {
-			continue
-		}
-		files = append(files, absPath)
-	}
 	bus.Publish(&BuildSuccessEvent{files})
 	slog.Info("tracked files")
 
@@ -409,7 +403,25 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	}
 	slog.Info("built config")
 
-	stream := make(chan events.EngineEvent)
+	configHash := ""
+	if input.Plan != nil || input.SavePlan != "" {
+		configHash, err = hashConfig(p.PathRoot(), files, appBytes, config)
+		if err != nil {
+			return err
+		}
+	}
+	if input.Plan != nil && input.Plan.ConfigHash != configHash {
+		return ErrPlanConfigChanged
+	}
+
+	var policy *Policy
+	if input.Command == "deploy" || input.Command == "diff" {
+		policy, err = ReadPolicy(p.PathPolicy())
+		if err != nil {
+			return err
+		}
+	}
+
 	eventlog, err := os.Create(p.PathLog("event"))
 	if err != nil {
 		return err
@@ -419,80 +431,108 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	errors := []Error{}
 	finished := false
 	importDiffs := map[string][]ImportDiff{}
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case event, ok := <-stream:
-				if !ok {
+	operations := []PlanOperation{}
+	policyFailed := false
+	timer := newReportTimer()
+	// watch handles the events of a single stack command. Every command needs
+	// its own stream since it's closed when the command completes.
+	watch := func(stream chan events.EngineEvent) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				select {
+				case <-ctx.Done():
 					return
-				}
-
-				if event.DiagnosticEvent != nil && event.DiagnosticEvent.Severity == "error" {
-					if strings.HasPrefix(event.DiagnosticEvent.Message, "update failed") {
-						break
+				case event, ok := <-stream:
+					if !ok {
+						return
 					}
-					if strings.Contains(event.DiagnosticEvent.Message, "failed to register new resource") {
-						break
+
+					if event.DiagnosticEvent != nil && event.DiagnosticEvent.Severity == "error" {
+						if strings.HasPrefix(event.DiagnosticEvent.Message, "update failed") {
+							break
+						}
+						if strings.Contains(event.DiagnosticEvent.Message, "failed to register new resource") {
+							break
+						}
+
+						// check if the error is a common error
+						help := commonErrorHelp(event.DiagnosticEvent.Message, event.DiagnosticEvent.URN)
+
+						errors = append(errors, Error{
+							Message: event.DiagnosticEvent.Message,
+							URN:     event.DiagnosticEvent.URN,
+							Help:    help,
+						})
+						telemetry.Track("cli.resource.error", map[string]interface{}{
+							"error": event.DiagnosticEvent.Message,
+							"urn":   event.DiagnosticEvent.URN,
+						})
 					}
 
-					// check if the error is a common error
-					help := []string{}
-					for _, commonError := range CommonErrors {
-						if strings.Contains(event.DiagnosticEvent.Message, commonError.Message) {
-							help = append(help, commonError.Short...)
+					if event.ResOpFailedEvent != nil {
+						if event.ResOpFailedEvent.Metadata.Op == apitype.OpImport {
+							for _, name := range event.ResOpFailedEvent.Metadata.Diffs {
+								old := event.ResOpFailedEvent.Metadata.Old.Inputs[name]
+								next := event.ResOpFailedEvent.Metadata.New.Inputs[name]
+								diffs, ok := importDiffs[event.ResOpFailedEvent.Metadata.URN]
+								if !ok {
+									diffs = []ImportDiff{}
+								}
+								importDiffs[event.ResOpFailedEvent.Metadata.URN] = append(diffs, ImportDiff{
+									URN:   event.ResOpFailedEvent.Metadata.URN,
+									Input: name,
+									Old:   old,
+									New:   next,
+								})
+							}
 						}
 					}
 
-					errors = append(errors, Error{
-						Message: event.DiagnosticEvent.Message,
-						URN:     event.DiagnosticEvent.URN,
-						Help:    help,
-					})
-					telemetry.Track("cli.resource.error", map[string]interface{}{
-						"error": event.DiagnosticEvent.Message,
-						"urn":   event.DiagnosticEvent.URN,
-					})
-				}
+					if event.ResourcePreEvent != nil && input.Command == "deploy" {
+						timer.start(event.ResourcePreEvent)
+					}
+					if event.ResOutputsEvent != nil && input.Command == "deploy" {
+						timer.complete(event.ResOutputsEvent.Metadata.URN, false)
+					}
+					if event.ResOpFailedEvent != nil && input.Command == "deploy" {
+						timer.complete(event.ResOpFailedEvent.Metadata.URN, true)
+					}
 
-				if event.ResOpFailedEvent != nil {
-					if event.ResOpFailedEvent.Metadata.Op == apitype.OpImport {
-						for _, name := range event.ResOpFailedEvent.Metadata.Diffs {
-							old := event.ResOpFailedEvent.Metadata.Old.Inputs[name]
-							next := event.ResOpFailedEvent.Metadata.New.Inputs[name]
-							diffs, ok := importDiffs[event.ResOpFailedEvent.Metadata.URN]
-							if !ok {
-								diffs = []ImportDiff{}
-							}
-							importDiffs[event.ResOpFailedEvent.Metadata.URN] = append(diffs, ImportDiff{
-								URN:   event.ResOpFailedEvent.Metadata.URN,
-								Input: name,
-								Old:   old,
-								New:   next,
-							})
+					if event.ResourcePreEvent != nil {
+						op := planOperation(event.ResourcePreEvent)
+						if isPlannedOp(op) {
+							operations = append(operations, op)
+						}
+						if input.Command == "diff" {
+							violations := applyPolicy(policy, event.ResourcePreEvent)
+							errors = append(errors, violations...)
+							policyFailed = policyFailed || len(violations) > 0
 						}
 					}
-				}
 
-				for _, field := range getNotNilFields(event) {
-					bus.Publish(field)
-				}
+					for _, field := range getNotNilFields(event) {
+						bus.Publish(field)
+					}
 
-				if event.SummaryEvent != nil {
-					finished = true
-				}
+					if event.SummaryEvent != nil {
+						finished = true
+					}
 
-				bytes, err := json.Marshal(event)
-				if err != nil {
-					return
+					bytes, err := json.Marshal(event)
+					if err != nil {
+						return
+					}
+					eventlog.Write(bytes)
+					eventlog.WriteString("\n")
 				}
-				eventlog.Write(bytes)
-				eventlog.WriteString("\n")
 			}
-		}
-	}()
+		}()
+		return done
+	}
+	stream := make(chan events.EngineEvent)
+	streamDone := watch(stream)
 
 	defer func() {
 		slog.Info("parsing state")
@@ -505,10 +545,19 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 		complete.Errors = errors
 		complete.ImportDiffs = importDiffs
 		defer bus.Publish(complete)
-		if input.Command == "diff" {
+		if readOnly {
 			return
 		}
 
+		if input.Command == "deploy" {
+			report := timer.report(runStarted, complete.Resources)
+			report.Command = input.Command
+			err = p.writeReport(report)
+			if err != nil {
+				slog.Error("failed to write report", "err", err)
+			}
+		}
+
 		outputsFilePath := filepath.Join(p.PathWorkingDir(), "outputs.json")
 		outputsFile, _ := os.Create(outputsFilePath)
 		defer outputsFile.Close()
@@ -520,7 +569,7 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	var summary auto.UpdateSummary
 	started := time.Now().Format(time.RFC3339)
 	defer func() {
-		if input.Command == "diff" {
+		if readOnly {
 			return
 		}
 		var parsed provider.Summary
@@ -610,16 +659,126 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 		}
 	}
 
-	switch input.Command {
-	case "deploy":
-		result, derr := stack.Up(ctx,
+	// excluding a resource means not targeting it, so its dependents can't
+	// be targeted automatically
+	targetDependents := len(input.Exclude) == 0
+	preview := func(stream chan<- events.EngineEvent) error {
+		_, err := stack.Preview(ctx, previewOptions(input, stream, pulumiLog, pulumiErrWriter)...)
+		return err
+	}
+	up := func(target []string, replace []string, stream chan events.EngineEvent) (auto.UpResult, error) {
+		opts := []optup.Option{
 			optup.DebugLogging(debugLogging),
-			optup.Target(input.Target),
-			optup.TargetDependents(),
+			optup.Target(target),
+			optup.Replace(replace),
 			optup.ProgressStreams(pulumiLog),
 			optup.ErrorProgressStreams(pulumiErrWriter),
 			optup.EventStreams(stream),
-		)
+		}
+		if targetDependents {
+			opts = append(opts, optup.TargetDependents())
+		}
+		if input.Parallel > 0 {
+			opts = append(opts, optup.Parallel(input.Parallel))
+		}
+		if input.ContinueOnError {
+			opts = append(opts, optup.ContinueOnError())
+		}
+		return stack.Up(ctx, opts...)
+	}
+
+	if input.Command == "deploy" && len(input.Exclude) > 0 {
+		slog.Info("excluding resources", "exclude", input.Exclude)
+		target, err := excludeTargets(input.Target, input.Exclude, func(stream chan<- events.EngineEvent) error {
+			_, err := stack.Preview(ctx,
+				optpreview.ProgressStreams(pulumiLog),
+				optpreview.ErrorProgressStreams(pulumiErrWriter),
+				optpreview.EventStreams(stream),
+			)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		input.Target = target
+	}
+
+	protect := len(p.app.Protect) > 0 && (input.Command == "deploy" || input.Command == "remove")
+	checkPolicy := policy != nil && input.Command == "deploy"
+	if input.Plan != nil || protect || checkPolicy {
+		slog.Info("previewing operations")
+		resources, err := previewResources(func(stream chan<- events.EngineEvent) error {
+			if input.Command == "remove" {
+				_, err := stack.PreviewDestroy(ctx,
+					optdestroy.Target(input.Target),
+					optdestroy.TargetDependents(),
+					optdestroy.ProgressStreams(pulumiLog),
+					optdestroy.ErrorProgressStreams(pulumiErrWriter),
+					optdestroy.EventStreams(stream),
+				)
+				return err
+			}
+			return preview(stream)
+		})
+		if err != nil {
+			return err
+		}
+		planned := plannedOperations(resources)
+		if input.Plan != nil {
+			err = input.Plan.Compare(planned)
+			if err != nil {
+				return err
+			}
+		}
+		if protect {
+			err = checkProtected(p.app.Protect, input.AllowDestroy, planned)
+			if err != nil {
+				return err
+			}
+		}
+		if checkPolicy {
+			slog.Info("checking policy")
+			for _, resource := range resources {
+				errors = append(errors, applyPolicy(policy, resource)...)
+			}
+			if len(errors) > 0 {
+				return ErrStackRunFailed
+			}
+		}
+	}
+
+	switch input.Command {
+	case "deploy":
+		result, derr := up(input.Target, input.Replace, stream)
+		for attempt := 1; derr != nil && attempt <= input.Retry; attempt++ {
+			<-streamDone
+			urns, ok := transientURNs(errors)
+			if !ok {
+				break
+			}
+			delay := retryDelay(attempt)
+			slog.Info("retrying transient failures", "attempt", attempt, "urns", urns, "delay", delay)
+			bus.Publish(&RetryEvent{Attempt: attempt, URNs: urns, Delay: delay})
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			errors = []Error{}
+			finished = false
+			stream = make(chan events.EngineEvent)
+			streamDone = watch(stream)
+			// only replace again if the replacement is what failed
+			replace := []string{}
+			for _, urn := range input.Replace {
+				if slices.Contains(urns, urn) {
+					replace = append(replace, urn)
+				}
+			}
+			result, derr = up(urns, replace, stream)
+		}
 		err = derr
 		summary = result.Summary
 
@@ -647,18 +806,50 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 		err = derr
 		summary = result.Summary
 	case "diff":
-		_, derr := stack.Preview(ctx,
+		// a saved plan is checked with the same preview, so they have to agree
+		opts := append(previewOptions(input, stream, pulumiLog, pulumiErrWriter),
 			optpreview.DebugLogging(debugLogging),
 			optpreview.Diff(),
-			optpreview.Target(input.Target),
-			optpreview.ProgressStreams(pulumiLog),
-			optpreview.ErrorProgressStreams(pulumiErrWriter),
-			optpreview.EventStreams(stream),
+		)
+		_, derr := stack.Preview(ctx, opts...)
+		err = derr
+		<-streamDone
+		if err == nil && policyFailed {
+			err = ErrStackRunFailed
+		}
+		if err == nil && input.SavePlan != "" {
+			plan := &Plan{
+				Version:    PlanVersion,
+				App:        p.app.Name,
+				Stage:      p.app.Stage,
+				Created:    time.Now().UTC().Format(time.RFC3339),
+				Target:     input.Target,
+				ConfigHash: configHash,
+				Operations: operations,
+			}
+			err = plan.Write(input.SavePlan)
+			if err != nil {
+				return err
+			}
+		}
+	case "drift":
+		_, derr := stack.PreviewRefresh(ctx,
+			optrefresh.DebugLogging(debugLogging),
+			optrefresh.Target(input.Target),
+			optrefresh.ProgressStreams(pulumiLog),
+			optrefresh.ErrorProgressStreams(pulumiErrWriter),
+			optrefresh.EventStreams(stream),
 		)
 		err = derr
 	}
 
 	slog.Info("done running stack command")
+	if context.Cause(ctx) == provider.ErrLockLost {
+		errors = append(errors, Error{
+			Message: provider.ErrLockLost.Error(),
+		})
+		return provider.ErrLockLost
+	}
 	if err != nil {
 		slog.Error("stack run failed", "error", err)
 		return ErrStackRunFailed
@@ -666,8 +857,60 @@ func (p *Project) Run(ctx context.Context, input *StackInput) error {
 	return nil
 }
 
+// previewOptions are the options of every preview of the stack, so a plan
+// saved by diff matches the preview deploy checks it against
+func previewOptions(input *StackInput, stream chan<- events.EngineEvent, progress io.Writer, errors io.Writer) []optpreview.Option {
+	opts := []optpreview.Option{
+		optpreview.Target(input.Target),
+		optpreview.Replace(input.Replace),
+		optpreview.ProgressStreams(progress),
+		optpreview.ErrorProgressStreams(errors),
+		optpreview.EventStreams(stream),
+	}
+	// excluded resources must not come back in as dependents of a target
+	if len(input.Exclude) == 0 {
+		opts = append(opts, optpreview.TargetDependents())
+	}
+	if input.Parallel > 0 {
+		opts = append(opts, optpreview.Parallel(input.Parallel))
+	}
+	return opts
+}
+
 func (p *Project) Lock(updateID string, command string) error {
-	return provider.Lock(p.home, updateID, p.Version(), command, p.app.Name, p.app.Stage)
+	return p.lockStage(updateID, command, nil)
+}
+
+// lockStage takes the lock and keeps it alive with a heartbeat. If the lock is lost
+// while running, cancel is called with provider.ErrLockLost and the state is no
+// longer pushed, so another update that took the lock over isn't clobbered.
+func (p *Project) lockStage(updateID string, command string, cancel context.CancelCauseFunc) error {
+	err := provider.Lock(p.home, updateID, p.Version(), command, p.app.Name, p.app.Stage)
+	if err != nil {
+		return err
+	}
+	lost := make(chan struct{})
+	p.lockLost = lost
+	p.stopHeartbeat = provider.Heartbeat(p.home, updateID, p.app.Name, p.app.Stage, func(err error) {
+		close(lost)
+		if cancel != nil {
+			cancel(err)
+		}
+	})
+	return nil
+}
+
+// lostLock reports whether the heartbeat found the lock released or taken over
+func (p *Project) lostLock() bool {
+	if p.lockLost == nil {
+		return false
+	}
+	select {
+	case <-p.lockLost:
+		return true
+	default:
+		return false
+	}
 }
 
 type PreviewInput struct {
@@ -682,6 +925,10 @@ type ImportOptions struct {
 }
 
 func (s *Project) Unlock() error {
+	if s.stopHeartbeat != nil {
+		s.stopHeartbeat()
+		s.stopHeartbeat = nil
+	}
 	if !flag.SST_NO_CLEANUP {
 		dir := s.PathWorkingDir()
 		files, err := os.ReadDir(dir)
@@ -697,6 +944,10 @@ func (s *Project) Unlock() error {
 			}
 		}
 	}
+	if s.lostLock() {
+		slog.Warn("not unlocking, the lock is held by another update")
+		return provider.ErrLockLost
+	}
 	return provider.Unlock(s.home, s.app.Name, s.app.Stage)
 }
 
@@ -724,7 +975,42 @@ func (s *Project) PullState() (string, error) {
 	return path, nil
 }
 
+// PullSnapshot replaces the local state with the snapshot taken after the
+// given update so it can be pushed back as the current state.
+func (s *Project) PullSnapshot(updateID string) (string, error) {
+	path, err := s.PullState()
+	if err != nil && !errors.Is(err, provider.ErrStateNotFound) {
+		return "", err
+	}
+	if path == "" {
+		path = filepath.Join(s.PathWorkingDir(), ".pulumi", "stacks", s.app.Name, fmt.Sprintf("%v.json", s.app.Stage))
+	}
+	// fetch into a temporary file so a failed fetch doesn't clobber the state
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	err = provider.PullSnapshot(s.home, s.app.Name, s.app.Stage, updateID, file)
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		file.Close()
+		return "", err
+	}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
 func (s *Project) PushState(version string) error {
+	if s.lostLock() {
+		slog.Warn("not pushing state, the lock is held by another update")
+		return provider.ErrLockLost
+	}
 	pulumiDir := filepath.Join(s.PathWorkingDir(), ".pulumi")
 	return provider.PushState(
 		s.home,
@@ -0,0 +1,37 @@
+package project
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+)
+
+var ErrExcludedEverything = fmt.Errorf("every resource is excluded")
+
+// excludeTargets turns the excluded URNs into a list of targets. Without any
+// targets it starts from every resource in the app.
+func excludeTargets(target []string, exclude []string, preview func(stream chan<- events.EngineEvent) error) ([]string, error) {
+	if len(target) == 0 {
+		resources, err := previewResources(preview)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			if slices.Contains(target, resource.Metadata.URN) {
+				continue
+			}
+			target = append(target, resource.Metadata.URN)
+		}
+	}
+	result := []string{}
+	for _, urn := range target {
+		if !slices.Contains(exclude, urn) {
+			result = append(result, urn)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrExcludedEverything
+	}
+	return result, nil
+}
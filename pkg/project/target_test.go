@@ -0,0 +1,65 @@
+package project
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// fakePreview sends a ResourcePreEvent for every URN, like a preview that
+// would touch those resources
+func fakePreview(urns ...string) func(stream chan<- events.EngineEvent) error {
+	return func(stream chan<- events.EngineEvent) error {
+		defer close(stream)
+		for _, urn := range urns {
+			stream <- events.EngineEvent{EngineEvent: apitype.EngineEvent{
+				ResourcePreEvent: &apitype.ResourcePreEvent{
+					Metadata: apitype.StepEventMetadata{URN: urn},
+				},
+			}}
+		}
+		return nil
+	}
+}
+
+func TestExcludeTargets(t *testing.T) {
+	urns := []string{"urn:api", "urn:bucket", "urn:web"}
+
+	t.Run("without target", func(t *testing.T) {
+		result, err := excludeTargets(nil, []string{"urn:bucket"}, fakePreview(urns...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result, []string{"urn:api", "urn:web"}) {
+			t.Errorf("expected every other resource to be targeted, got %v", result)
+		}
+	})
+
+	t.Run("with target", func(t *testing.T) {
+		preview := func(stream chan<- events.EngineEvent) error {
+			t.Fatal("expected no preview when targets are given")
+			return nil
+		}
+		result, err := excludeTargets([]string{"urn:api", "urn:bucket"}, []string{"urn:bucket"}, preview)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result, []string{"urn:api"}) {
+			t.Errorf("expected only the remaining target, got %v", result)
+		}
+	})
+
+	t.Run("everything excluded", func(t *testing.T) {
+		_, err := excludeTargets(nil, urns, fakePreview(urns...))
+		if !errors.Is(err, ErrExcludedEverything) {
+			t.Errorf("expected ErrExcludedEverything, got %v", err)
+		}
+		_, err = excludeTargets([]string{"urn:api"}, []string{"urn:api"}, fakePreview(urns...))
+		if !errors.Is(err, ErrExcludedEverything) {
+			t.Errorf("expected ErrExcludedEverything, got %v", err)
+		}
+	})
+}
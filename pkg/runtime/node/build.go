@@ -15,6 +15,7 @@ import (
 	"github.com/sst/ion/internal/fs"
 	"github.com/sst/ion/pkg/js"
 	"github.com/sst/ion/pkg/process"
+	"github.com/sst/ion/pkg/project/path"
 	"github.com/sst/ion/pkg/runtime"
 )
 
@@ -97,7 +98,7 @@ func (r *Runtime) Build(ctx context.Context, input *runtime.BuildInput) (*runtim
 		},
 	}
 	if properties.Plugins != "" {
-		plugins = append(plugins, plugin(properties.Plugins))
+		plugins = append(plugins, plugin(path.ResolvePlatformDir(input.CfgPath), properties.Plugins))
 	}
 	external := append(forceExternal, properties.Install...)
 	external = append(external, properties.ESBuild.External...)
@@ -25,14 +25,14 @@ type request struct {
 	Value   map[string]any
 }
 
-func plugin(path string) api.Plugin {
+func plugin(platformDir string, path string) api.Plugin {
 	cwd, _ := os.Getwd()
 	path = filepath.Join(cwd, path)
 	return api.Plugin{
 		Name: "nodejs-plugin",
 		Setup: func(build api.PluginBuild) {
 			slog.Info("nodejs plugin", "path", path)
-			cmd := process.Command("node", ".sst/platform/functions/nodejs-runtime/plugin.mjs", path)
+			cmd := process.Command("node", filepath.Join(platformDir, "functions/nodejs-runtime/plugin.mjs"), path)
 			var wg errgroup.Group
 			// cmd.Stderr = os.Stderr
 			stdin, err := cmd.StdinPipe()
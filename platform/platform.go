@@ -16,6 +16,9 @@ var files embed.FS
 //go:embed templates/*
 var Templates embed.FS
 
+//go:embed common-errors.json
+var CommonErrors []byte
+
 func CopyTo(srcDir, destDir string) error {
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return err
@@ -1347,6 +1347,7 @@ export class Function extends Component implements Link.Linkable {
         handler: args.handler,
         internal: args._skipMetadata,
         dev: dev,
+        links,
       },
       _hint: args._skipHint
         ? undefined
@@ -150,6 +150,27 @@ export interface App {
    * ```
    */
   removal?: "remove" | "retain" | "retain-all";
+  /**
+   * A list of resource URNs or types that are never deleted or replaced by accident.
+   *
+   * Before `sst deploy` or `sst remove` makes any changes, it checks which resources would be
+   * deleted or replaced. If any of them match this list, it fails without changing anything.
+   * This is useful for resources with data in them, like databases, buckets, and hosted zones,
+   * that are easy to replace by renaming them.
+   *
+   * To delete or replace a protected resource on purpose, pass in its URN with `--allow-destroy`.
+   *
+   * @example
+   * Protect all RDS instances, S3 buckets, and Route 53 zones in _production_.
+   * ```ts
+   * {
+   *   protect: input.stage === "production"
+   *     ? ["aws:rds/instance:Instance", "aws:s3/bucketV2:BucketV2", "aws:route53/zone:Zone"]
+   *     : []
+   * }
+   * ```
+   */
+  protect?: string[];
   /**
    * The providers that are being used in this app. This allows you to use the resources from
    * these providers in your app.
@@ -206,7 +227,7 @@ export interface App {
    * The provider SST will use to store the state for your app. The state keeps track of all your resources and secrets. The state is generated locally and backed up in your cloud provider.
    *
    *
-   * Currently supports AWS, Cloudflare and local.
+   * Currently supports AWS, Cloudflare, any S3 compatible object store, and local.
    *
    * :::tip
    * SST uses the `home` provider to store the state for your app. If you use the local provider it will be saved on your machine. You can see where by running `sst version`.
@@ -225,8 +246,21 @@ export interface App {
    * }
    * ```
    *
+   * To store the state in an S3 compatible object store like MinIO, set `home` to `s3` and
+   * configure it with environment variables.
+   *
+   * ```bash
+   * SST_HOME_S3_ENDPOINT=http://localhost:9000
+   * SST_HOME_S3_BUCKET=sst-state
+   * SST_HOME_S3_REGION=us-east-1
+   * SST_HOME_S3_ACCESS_KEY_ID=minioadmin
+   * SST_HOME_S3_SECRET_ACCESS_KEY=minioadmin
+   * ```
+   *
+   * The passphrase used to encrypt the state is stored in the bucket. If `SST_HOME_S3_PASSPHRASE`
+   * is set, it's used for every stage instead and is never written to the bucket.
    */
-  home: "aws" | "cloudflare" | "local";
+  home: "aws" | "cloudflare" | "local" | "s3";
 }
 
 export interface AppInput {
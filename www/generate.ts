@@ -325,7 +325,7 @@ function generateCommonErrorsDoc() {
         "Common Errors",
         "A list of CLI error messages and how to fix them."
       ),
-      renderSourceMessage("cmd/sst/main.go"),
+      renderSourceMessage("platform/common-errors.json"),
       renderImports(outputFilePath),
       renderBodyBegin(),
       renderCommonErrorsAbout(),